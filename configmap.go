@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+var keysPermittedStates = []ResourceState{ResourceExists}
+
+// ConfigMapMatcher matches config maps that exist and hold the description's keys.
+type ConfigMapMatcher struct {
+	*informerMatcher
+}
+
+// KeysValidator validates descriptions of resources that hold keys, config maps and secrets.
+type KeysValidator struct {
+	BaseValidator
+}
+
+func (v *KeysValidator) Validate(ctx context.Context, description StateDescription) error {
+	err := v.BaseValidator.Validate(ctx, description)
+	if err != nil {
+		return err
+	}
+	return validateStates(description, keysPermittedStates)
+}
+
+func NewKeysValidator() Validator {
+	return &KeysValidator{}
+}
+
+func NewConfigMapMatcher(informers *InformerCache, description StateDescription) Matcher {
+	m := &ConfigMapMatcher{}
+	m.informerMatcher = newInformerMatcher(informers, description, m.evaluate)
+	return m
+}
+
+func (m *ConfigMapMatcher) evaluate(obj interface{}) (ResourceState, string) {
+	configMap := obj.(*v1.ConfigMap)
+	sizes := make(map[string]int, len(configMap.Data)+len(configMap.BinaryData))
+	for key, value := range configMap.Data {
+		sizes[key] = len(value)
+	}
+	for key, value := range configMap.BinaryData {
+		sizes[key] = len(value)
+	}
+	return evaluateKeys(m.description, sizes)
+}
+
+// evaluateKeys returns Exists if a resource holds every key of the description, with a
+// non-empty value if NonEmptyKeys is set. sizes holds the length of the value of each key.
+func evaluateKeys(description StateDescription, sizes map[string]int) (ResourceState, string) {
+	var missing, empty []string
+	for _, key := range description.Keys {
+		size, ok := sizes[key]
+		switch {
+		case !ok:
+			missing = append(missing, key)
+		case size == 0 && description.NonEmptyKeys:
+			empty = append(empty, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(empty)
+	var reasons []string
+	if len(missing) > 0 {
+		reasons = append(reasons, fmt.Sprintf("missing keys %s", strings.Join(missing, ", ")))
+	}
+	if len(empty) > 0 {
+		reasons = append(reasons, fmt.Sprintf("empty keys %s", strings.Join(empty, ", ")))
+	}
+	if len(reasons) > 0 {
+		return resourceWaiting, strings.Join(reasons, "; ")
+	}
+	return ResourceExists, ""
+}
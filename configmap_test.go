@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEvaluateKeys(t *testing.T) {
+	sizes := map[string]int{"username": 5, "password": 0}
+	tests := []struct {
+		keys     []string
+		nonEmpty bool
+		expected ResourceState
+		reason   string
+	}{
+		{nil, false, ResourceExists, ""},
+		{[]string{"username", "password"}, false, ResourceExists, ""},
+		{[]string{"username", "password"}, true, resourceWaiting, "empty keys password"},
+		{[]string{"token", "password", "ca.crt"}, true, resourceWaiting, "missing keys ca.crt, token; empty keys password"},
+	}
+	for _, test := range tests {
+		state, reason := evaluateKeys(StateDescription{Keys: test.keys, NonEmptyKeys: test.nonEmpty}, sizes)
+		if state != test.expected || reason != test.reason {
+			t.Errorf("%v: expected %s %q, got %s %q", test.keys, test.expected, test.reason, state, reason)
+		}
+	}
+}
+
+func TestKeysValidator(t *testing.T) {
+	description := StateDescription{Type: PodResource, RequiredStates: []ResourceState{ResourceReady}, Keys: []string{"a"}}
+	if err := NewPodValidator().Validate(context.Background(), description); err == nil {
+		t.Error("expected keys to be rejected for pods")
+	}
+	description = StateDescription{Type: SecretResource, RequiredStates: []ResourceState{ResourceReady}}
+	if err := NewKeysValidator().Validate(context.Background(), description); err == nil {
+		t.Error("expected Ready to be rejected for secrets")
+	}
+	description.RequiredStates = []ResourceState{ResourceExists}
+	description.Keys = []string{"a"}
+	if err := NewKeysValidator().Validate(context.Background(), description); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidatorTypeFields(t *testing.T) {
+	maxRestarts, minSucceeded := int32(3), int32(1)
+	tests := []struct {
+		description StateDescription
+		field       string
+	}{
+		{StateDescription{Type: NamespaceResource, Container: "app", RequiredStates: []ResourceState{ResourceActive}}, "container"},
+		{StateDescription{Type: SecretResource, Image: "registry/api:*", RequiredStates: []ResourceState{ResourceExists}}, "image"},
+		{StateDescription{Type: ConfigMapResource, Run: CronJobRunNext, RequiredStates: []ResourceState{ResourceExists}}, "run"},
+		{StateDescription{Type: NodeResource, MinSucceeded: &minSucceeded, RequiredStates: []ResourceState{ResourceSchedulable}}, "minSucceeded"},
+		{StateDescription{Type: JobResource, MaxRestarts: &maxRestarts, RequiredStates: []ResourceState{ResourceComplete}}, "maxRestarts"},
+		{StateDescription{Type: PodResource, NonEmptyKeys: true, RequiredStates: []ResourceState{ResourceReady}}, "nonEmptyKeys"},
+	}
+	for _, test := range tests {
+		validator, _ := getValidator(nil, test.description)
+		expected := ErrFieldNotValidForResourceType(test.description, test.field)
+		if err := validator.Validate(context.Background(), test.description); err == nil || err.Error() != expected.Error() {
+			t.Errorf("%v: expected %v, got %v", test.description, expected, err)
+		}
+	}
+}
+
+func TestSecretMatcher(t *testing.T) {
+	fake := fakeclientset.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("admin")},
+	})
+	matcher := NewSecretMatcher(NewInformerCache(fake), StateDescription{
+		Type:           SecretResource,
+		Namespace:      "default",
+		Name:           "db-credentials",
+		Keys:           []string{"username", "password"},
+		RequiredStates: []ResourceState{ResourceExists},
+	})
+	result := startMatcher(context.Background(), matcher)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+	}
+	if _, err := fake.CoreV1().Secrets("default").Update(secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := expectStartReturns(t, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNamespaceMatcher(t *testing.T) {
+	fake := fakeclientset.NewSimpleClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"},
+		Status:     v1.NamespaceStatus{Phase: v1.NamespaceActive},
+	})
+	matcher := NewNamespaceMatcher(NewInformerCache(fake), StateDescription{
+		Type:           NamespaceResource,
+		Name:           "tenant-a",
+		RequiredStates: []ResourceState{ResourceActive},
+	})
+	if err := expectStartReturns(t, startMatcher(context.Background(), matcher)); err != nil {
+		t.Fatal(err)
+	}
+}
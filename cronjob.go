@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// CronJobRunLatest matches the state of the most recent job of a cron job.
+	CronJobRunLatest = "latest"
+	// CronJobRunNext matches the state of the first job created after kubewait started.
+	CronJobRunNext = "next"
+)
+
+// CronJobMatcher matches cron jobs by the state of one of the jobs they own, chosen by the
+// description's Run field.
+type CronJobMatcher struct {
+	*informerMatcher
+	// started is when the matcher was created, before which CronJobRunNext ignores jobs.
+	started time.Time
+	// createdAfter is the description's resolved CreatedAfter, which applies to the jobs
+	// rather than to the long-lived cron job.
+	createdAfter time.Time
+	jobs         cache.Store
+}
+
+// CronJobValidator validates the states of a cron job like those of a job, as a cron job is in
+// the state of one of its jobs.
+type CronJobValidator struct {
+	JobValidator
+}
+
+func (v *CronJobValidator) Validate(ctx context.Context, description StateDescription) error {
+	err := v.JobValidator.Validate(ctx, description)
+	if err != nil {
+		return err
+	}
+	switch description.Run {
+	case "", CronJobRunLatest, CronJobRunNext:
+	default:
+		return ErrInvalidCronJobRun(description)
+	}
+	return nil
+}
+
+func NewCronJobValidator() Validator {
+	return &CronJobValidator{}
+}
+
+func NewCronJobMatcher(informers *InformerCache, description StateDescription) Matcher {
+	m := &CronJobMatcher{started: time.Now(), createdAfter: description.createdAfter}
+	description.createdAfter = time.Time{}
+	m.informerMatcher = newInformerMatcher(informers, description, m.evaluate)
+	return m
+}
+
+// Start follows the jobs in the namespace of the cron jobs, so that a cron job is evaluated
+// again whenever one of its jobs changes.
+func (m *CronJobMatcher) Start(ctx context.Context) error {
+	informer, err := m.follow(JobResource, m.description.InformerNamespace())
+	if err != nil {
+		return err
+	}
+	m.jobs = informer.GetStore()
+	if synced, err := informer.waitForSync(ctx, m.done); !synced {
+		return err
+	}
+	return m.informerMatcher.Start(ctx)
+}
+
+func (m *CronJobMatcher) evaluate(obj interface{}) (ResourceState, string) {
+	cronJob := obj.(*batchv1beta1.CronJob)
+	job := m.selectJob(cronJob)
+	if job == nil {
+		if notBefore := m.notBefore(); !notBefore.IsZero() {
+			return resourceWaiting, fmt.Sprintf("no run since %s", notBefore.UTC().Format(time.RFC3339))
+		}
+		return resourceWaiting, "no runs yet"
+	}
+	state := getJobResourceState(job, m.description.MinSucceeded)
+	if reason := explainJob(job); reason != "" {
+		return state, fmt.Sprintf("job %s: %s", job.Name, reason)
+	}
+	return state, ""
+}
+
+// notBefore returns the time before which jobs are ignored: the later of createdAfter and, for
+// CronJobRunNext, when the matcher started. It is zero if no job is ignored.
+func (m *CronJobMatcher) notBefore() time.Time {
+	if m.description.Run == CronJobRunNext && m.started.After(m.createdAfter) {
+		return m.started
+	}
+	return m.createdAfter
+}
+
+// selectJob returns the most recent job controlled by cronJob or, for CronJobRunNext, the
+// first one created after the matcher started. Jobs created before createdAfter are ignored.
+// It returns nil if there is no such job.
+func (m *CronJobMatcher) selectJob(cronJob *batchv1beta1.CronJob) *batchv1.Job {
+	// creation timestamps only have a precision of seconds
+	notBefore := metav1.NewTime(m.notBefore().Truncate(time.Second))
+	var selected *batchv1.Job
+	for _, obj := range m.jobs.List() {
+		job := obj.(*batchv1.Job)
+		owner := metav1.GetControllerOf(job)
+		if owner == nil || owner.UID != cronJob.UID {
+			continue
+		}
+		if job.CreationTimestamp.Before(&notBefore) {
+			continue
+		}
+		switch {
+		case selected == nil:
+		case m.description.Run == CronJobRunNext && isNewerJob(selected, job):
+		case m.description.Run != CronJobRunNext && isNewerJob(job, selected):
+		default:
+			continue
+		}
+		selected = job
+	}
+	return selected
+}
+
+// isNewerJob reports whether a was created after b, using the name to break ties.
+func isNewerJob(a, b *batchv1.Job) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return b.CreationTimestamp.Before(&a.CreationTimestamp)
+	}
+	return a.Name > b.Name
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestCronJobRun(cronJob *batchv1beta1.CronJob, name string, created time.Time, complete bool) *batchv1.Job {
+	controller := true
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         cronJob.Namespace,
+			CreationTimestamp: metav1.NewTime(created),
+			OwnerReferences: []metav1.OwnerReference{
+				metav1.OwnerReference{Kind: "CronJob", Name: cronJob.Name, UID: cronJob.UID, Controller: &controller},
+			},
+		},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+	if complete {
+		job.Status = batchv1.JobStatus{
+			Succeeded:  1,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+		}
+	}
+	return job
+}
+
+func TestCronJobMatcher(t *testing.T) {
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "loader", Namespace: "test-ns", UID: types.UID("loader-uid")},
+	}
+	lastNight := newTestCronJobRun(cronJob, "loader-1", time.Now().Add(-24*time.Hour), true)
+	fake := fakeclientset.NewSimpleClientset(cronJob, lastNight)
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	latest := NewCronJobMatcher(informers, StateDescription{
+		Type:           CronJobResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceComplete},
+	})
+	if err := expectStartReturns(t, startMatcher(context.Background(), latest)); err != nil {
+		t.Fatal(err)
+	}
+
+	next := NewCronJobMatcher(informers, StateDescription{
+		Type:           CronJobResource,
+		Namespace:      "test-ns",
+		Run:            CronJobRunNext,
+		RequiredStates: []ResourceState{ResourceComplete},
+	})
+	result := startMatcher(context.Background(), next)
+	time.Sleep(200 * time.Millisecond)
+	if state := next.States()["test-ns/loader"]; state != resourceWaiting {
+		t.Fatalf("last night's run should not match the next run, got %s", state)
+	}
+
+	tonight := newTestCronJobRun(cronJob, "loader-2", time.Now().Add(time.Second), false)
+	if _, err := fake.BatchV1().Jobs("test-ns").Create(tonight); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if state := next.States()["test-ns/loader"]; state != ResourceRunning {
+		t.Fatalf("expected tonight's run to be running, got %s", state)
+	}
+	tonight = newTestCronJobRun(cronJob, "loader-2", time.Now().Add(time.Second), true)
+	if _, err := fake.BatchV1().Jobs("test-ns").Update(tonight); err != nil {
+		t.Fatal(err)
+	}
+	if err := expectStartReturns(t, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCronJobMatcherCreatedAfter(t *testing.T) {
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "loader",
+			Namespace:         "test-ns",
+			UID:               types.UID("loader-uid"),
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour)),
+		},
+	}
+	lastNight := newTestCronJobRun(cronJob, "loader-1", time.Now().Add(-24*time.Hour), true)
+	fake := fakeclientset.NewSimpleClientset(cronJob, lastNight)
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	description := StateDescription{
+		Type:           CronJobResource,
+		Namespace:      "test-ns",
+		CreatedAfter:   "2h",
+		RequiredStates: []ResourceState{ResourceComplete},
+	}
+	descriptions := []StateDescription{description}
+	if err := ResolveCreatedAfter(fake, descriptions, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	matcher := NewCronJobMatcher(informers, descriptions[0])
+	result := startMatcher(context.Background(), matcher)
+	time.Sleep(200 * time.Millisecond)
+	if state, ok := matcher.States()["test-ns/loader"]; !ok || state != resourceWaiting {
+		t.Fatalf("the month-old cron job should be waiting for a run in the last 2h, got %q", state)
+	}
+
+	recent := newTestCronJobRun(cronJob, "loader-2", time.Now().Add(-time.Hour), true)
+	if _, err := fake.BatchV1().Jobs("test-ns").Create(recent); err != nil {
+		t.Fatal(err)
+	}
+	if err := expectStartReturns(t, result); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -31,3 +31,101 @@ func ErrStateNotValidForResourceType(description StateDescription, state Resourc
 		StateDescription: description,
 	}
 }
+
+func ErrInvalidSelector(description StateDescription, err error) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("invalid selector: %v", err),
+		StateDescription: description,
+	}
+}
+
+func ErrInvalidNameGlob(description StateDescription, err error) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("invalid \"nameGlob\": %v", err),
+		StateDescription: description,
+	}
+}
+
+func ErrNamespaceSelectorWithNamespace(description StateDescription) error {
+	return &ValidationError{
+		Message:          "\"namespaceSelector\" cannot be combined with a \"namespace\" other than \"*\"",
+		StateDescription: description,
+	}
+}
+
+func ErrFieldNotValidForResourceType(description StateDescription, field string) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("\"%s\" is not valid for resource type \"%s\"", field, description.Type),
+		StateDescription: description,
+	}
+}
+
+func ErrSucceededWithoutMinSucceeded(description StateDescription) error {
+	return &ValidationError{
+		Message:          "\"Succeeded\" state requires \"minSucceeded\" for jobs",
+		StateDescription: description,
+	}
+}
+
+func ErrMinSucceededWithoutSucceeded(description StateDescription) error {
+	return &ValidationError{
+		Message:          "\"minSucceeded\" requires the \"Succeeded\" state in \"requiredStates\" or \"failOn\"",
+		StateDescription: description,
+	}
+}
+
+func ErrInvalidMinCount(description StateDescription) error {
+	return &ValidationError{
+		Message:          "\"minCount\" must be at least 1",
+		StateDescription: description,
+	}
+}
+
+func ErrInvalidCronJobRun(description StateDescription) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("\"run\" must be %q or %q", CronJobRunLatest, CronJobRunNext),
+		StateDescription: description,
+	}
+}
+
+func ErrInvalidCreatedAfter(description StateDescription, err error) error {
+	return &ValidationError{
+		Message:          err.Error(),
+		StateDescription: description,
+	}
+}
+
+func ErrInvalidImage(description StateDescription, err error) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("invalid \"image\": %v", err),
+		StateDescription: description,
+	}
+}
+
+func ErrInvalidMinVersion(description StateDescription, err error) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("invalid \"minVersion\": %v", err),
+		StateDescription: description,
+	}
+}
+
+func ErrStateRequiredAndFailed(description StateDescription, state ResourceState) error {
+	return &ValidationError{
+		Message:          fmt.Sprintf("\"%s\" state is both required and in \"failOn\"", state),
+		StateDescription: description,
+	}
+}
+
+// FailedStateError is returned by a matcher when a resource reaches one of the FailOn states.
+type FailedStateError struct {
+	Resource string
+	State    ResourceState
+	Reason   string
+}
+
+func (f *FailedStateError) Error() string {
+	if f.Reason != "" {
+		return fmt.Sprintf("%s is %s: %s", f.Resource, f.State, f.Reason)
+	}
+	return fmt.Sprintf("%s is %s", f.Resource, f.State)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// PodNameEnv, PodNamespaceEnv and PodUIDEnv identify the pod kubewait runs in.
+	// They are expected to be populated through the downward API.
+	PodNameEnv      = "POD_NAME"
+	PodNamespaceEnv = "POD_NAMESPACE"
+	PodUIDEnv       = "POD_UID"
+)
+
+const (
+	ReasonWaitingForDependency = "WaitingForDependency"
+	ReasonDependencyMatched    = "DependencyMatched"
+	ReasonDependencyFailed     = "DependencyFailed"
+	ReasonWaitTimedOut         = "WaitTimedOut"
+)
+
+// eventFlushDelay is how long Shutdown gives the broadcaster to post the last events.
+// The broadcaster has no way to flush, and kubewait exits as soon as it is done.
+const eventFlushDelay = time.Second
+
+// EventReporter posts Kubernetes events on kubewait's own pod. A nil *EventReporter
+// discards all events.
+type EventReporter struct {
+	sink     watch.Interface
+	recorder record.EventRecorder
+	pod      *v1.ObjectReference
+}
+
+// NewEventReporterFromEnv creates an EventReporter for the pod described by the downward API
+// environment variables. It returns nil if the pod name or namespace is not available.
+func NewEventReporterFromEnv(clientset kubernetes.Interface) *EventReporter {
+	name, _ := os.LookupEnv(PodNameEnv)
+	namespace, _ := os.LookupEnv(PodNamespaceEnv)
+	if name == "" || namespace == "" {
+		log.Debugf("%s or %s not set, not recording events", PodNameEnv, PodNamespaceEnv)
+		return nil
+	}
+	uid, _ := os.LookupEnv(PodUIDEnv)
+
+	broadcaster := record.NewBroadcaster()
+	// The sink rate limits events per object through the default EventCorrelator.
+	sink := broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: clientset.CoreV1().Events(namespace),
+	})
+	return &EventReporter{
+		sink:     sink,
+		recorder: broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "kubewait"}),
+		pod: &v1.ObjectReference{
+			Kind:       "Pod",
+			APIVersion: "v1",
+			Name:       name,
+			Namespace:  namespace,
+			UID:        types.UID(uid),
+		},
+	}
+}
+
+// Eventf records an event on kubewait's pod.
+func (r *EventReporter) Eventf(eventtype, reason, messageFmt string, args ...interface{}) {
+	if r == nil {
+		return
+	}
+	r.recorder.Eventf(r.pod, eventtype, reason, messageFmt, args...)
+}
+
+// Shutdown stops recording to the API server after giving it a moment to post pending events.
+func (r *EventReporter) Shutdown() {
+	if r == nil {
+		return
+	}
+	time.Sleep(eventFlushDelay)
+	r.sink.Stop()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+)
+
+// explainPod returns a human readable explanation of why a pod is not ready, derived from its
+// conditions and container statuses. It returns an empty string for pods that are ready or succeeded.
+func explainPod(pod *v1.Pod) string {
+	switch getPodResourceState(pod) {
+	case ResourceReady, ResourceSucceeded:
+		return ""
+	}
+	if pod.Status.Phase == v1.PodFailed {
+		return joinReason("pod failed", pod.Status.Reason, pod.Status.Message)
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse {
+			return joinReason("not scheduled", condition.Reason, condition.Message)
+		}
+	}
+	if reason := explainContainers("init container", pod.Status.InitContainerStatuses); reason != "" {
+		return reason
+	}
+	if reason := explainContainers("container", pod.Status.ContainerStatuses); reason != "" {
+		return reason
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady && condition.Status != v1.ConditionTrue {
+			return joinReason("not ready", condition.Reason, condition.Message)
+		}
+	}
+	return fmt.Sprintf("pod is %s", strings.ToLower(string(pod.Status.Phase)))
+}
+
+// explainContainers explains the first container that is holding the pod back.
+func explainContainers(kind string, statuses []v1.ContainerStatus) string {
+	for _, status := range statuses {
+		prefix := fmt.Sprintf("%s %s", kind, status.Name)
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+			return joinReason(prefix, status.State.Waiting.Reason, status.State.Waiting.Message)
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+			terminated := status.State.Terminated
+			return joinReason(prefix, terminated.Reason, fmt.Sprintf("exit code %d", terminated.ExitCode))
+		case status.State.Running != nil && !status.Ready:
+			return fmt.Sprintf("%s is running but not ready", prefix)
+		}
+	}
+	return ""
+}
+
+// explainContainer returns a human readable explanation of why the named container is not
+// ready. It returns an empty string for containers that are ready or exited successfully.
+func explainContainer(name string, status *v1.ContainerStatus, maxRestarts *int32) string {
+	prefix := fmt.Sprintf("container %s", name)
+	switch getContainerResourceState(status, maxRestarts) {
+	case ResourceReady, ResourceSucceeded:
+		return ""
+	case ResourceRestartLimitExceeded:
+		return fmt.Sprintf("%s restarted %d times (maxRestarts %d)", prefix, status.RestartCount, *maxRestarts)
+	}
+	if status == nil {
+		return fmt.Sprintf("%s has not started", prefix)
+	}
+	if reason := explainContainers("container", []v1.ContainerStatus{*status}); reason != "" {
+		return reason
+	}
+	return fmt.Sprintf("%s is waiting", prefix)
+}
+
+// explainJob returns a human readable explanation of why a job has not completed.
+// It returns an empty string for completed jobs.
+func explainJob(job *batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return ""
+		case batchv1.JobFailed:
+			return joinReason("job failed", condition.Reason, condition.Message)
+		case jobSuspended:
+			return joinReason("job suspended", condition.Reason, condition.Message)
+		}
+	}
+	progress := fmt.Sprintf("%d active, %d succeeded", job.Status.Active, job.Status.Succeeded)
+	if job.Spec.Completions != nil {
+		progress = fmt.Sprintf("%s of %d", progress, *job.Spec.Completions)
+	}
+	if job.Status.Failed > 0 {
+		progress = fmt.Sprintf("%s, %d failed", progress, job.Status.Failed)
+		if job.Spec.BackoffLimit != nil {
+			progress = fmt.Sprintf("%s (backoffLimit %d)", progress, *job.Spec.BackoffLimit)
+		}
+	}
+	if job.Status.Active == 0 && job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+		return "no pods started"
+	}
+	return progress
+}
+
+// explainNode returns a human readable explanation of why a node is not schedulable.
+// It returns an empty string for schedulable nodes.
+func explainNode(node *v1.Node) string {
+	state := getNodeResourceState(node)
+	for _, condition := range node.Status.Conditions {
+		switch {
+		case state == resourceWaiting && condition.Type == v1.NodeReady:
+			return joinReason("not ready", condition.Reason, condition.Message)
+		case condition.Type != v1.NodeReady && string(condition.Type) == string(state):
+			return joinReason(string(condition.Type), condition.Reason, condition.Message)
+		}
+	}
+	if state == resourceWaiting {
+		return "not ready"
+	}
+	return ""
+}
+
+// joinReason formats a prefix followed by whichever of reason and message are set.
+func joinReason(prefix, reason, message string) string {
+	parts := []string{prefix}
+	for _, part := range []string{reason, message} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ": ")
+}
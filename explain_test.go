@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+)
+
+func TestExplainPod(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   v1.PodStatus
+		expected string
+	}{
+		{
+			name: "ready",
+			status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				Conditions: []v1.PodCondition{
+					v1.PodCondition{Type: v1.PodReady, Status: v1.ConditionTrue},
+				},
+			},
+			expected: "",
+		},
+		{
+			name: "unschedulable",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				Conditions: []v1.PodCondition{
+					v1.PodCondition{
+						Type:    v1.PodScheduled,
+						Status:  v1.ConditionFalse,
+						Reason:  "Unschedulable",
+						Message: "0/3 nodes are available: 3 Insufficient cpu.",
+					},
+				},
+			},
+			expected: "not scheduled: Unschedulable: 0/3 nodes are available: 3 Insufficient cpu.",
+		},
+		{
+			name: "crash loop",
+			status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					v1.ContainerStatus{
+						Name: "app",
+						State: v1.ContainerState{
+							Waiting: &v1.ContainerStateWaiting{
+								Reason:  "CrashLoopBackOff",
+								Message: "back-off 5m0s restarting failed container",
+							},
+						},
+					},
+				},
+			},
+			expected: "container app: CrashLoopBackOff: back-off 5m0s restarting failed container",
+		},
+		{
+			name: "image pull",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				InitContainerStatuses: []v1.ContainerStatus{
+					v1.ContainerStatus{
+						Name: "init",
+						State: v1.ContainerState{
+							Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+						},
+					},
+				},
+			},
+			expected: "init container init: ImagePullBackOff",
+		},
+		{
+			name: "evicted",
+			status: v1.PodStatus{
+				Phase:   v1.PodFailed,
+				Reason:  "Evicted",
+				Message: "The node was low on resource: memory.",
+			},
+			expected: "pod failed: Evicted: The node was low on resource: memory.",
+		},
+		{
+			name: "not ready",
+			status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					v1.ContainerStatus{
+						Name:  "app",
+						State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+					},
+				},
+			},
+			expected: "container app is running but not ready",
+		},
+	}
+	for _, test := range tests {
+		if reason := explainPod(&v1.Pod{Status: test.status}); reason != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, reason)
+		}
+	}
+}
+
+func TestExplainJob(t *testing.T) {
+	completions, backoffLimit := int32(3), int32(6)
+	failed := &batchv1.Job{
+		Status: batchv1.JobStatus{
+			Failed: 7,
+			Conditions: []batchv1.JobCondition{
+				batchv1.JobCondition{
+					Type:    batchv1.JobFailed,
+					Status:  v1.ConditionTrue,
+					Reason:  "BackoffLimitExceeded",
+					Message: "Job has reached the specified backoff limit",
+				},
+			},
+		},
+	}
+	if reason := explainJob(failed); reason != "job failed: BackoffLimitExceeded: Job has reached the specified backoff limit" {
+		t.Errorf("unexpected explanation for failed job: %q", reason)
+	}
+
+	retrying := &batchv1.Job{
+		Spec: batchv1.JobSpec{
+			Completions:  &completions,
+			BackoffLimit: &backoffLimit,
+		},
+		Status: batchv1.JobStatus{Active: 1, Succeeded: 1, Failed: 2},
+	}
+	if reason := explainJob(retrying); reason != "1 active, 1 succeeded of 3, 2 failed (backoffLimit 6)" {
+		t.Errorf("unexpected explanation for retrying job: %q", reason)
+	}
+
+	if reason := explainJob(&batchv1.Job{}); reason != "no pods started" {
+		t.Errorf("unexpected explanation for new job: %q", reason)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilwait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+)
+
+// newBackoff returns the backoff applied between failed list calls, on top of the
+// informer's own retry period. It is a variable so that tests can shorten it.
+var newBackoff = func() utilwait.Backoff {
+	return utilwait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    10,
+		Cap:      time.Minute,
+	}
+}
+
+// listPageSize is the most objects an informer requests in a single list call.
+var listPageSize int64 = 500
+
+// syncPollPeriod is how often a matcher checks whether its informer has synced.
+const syncPollPeriod = 100 * time.Millisecond
+
+// InformerCache shares one informer per resource type, namespace and field selector between
+// matchers, so descriptions that watch the same kind of resource in the same namespace share
+// a single list, watch and cache. Field selectors are applied by the API server, while label
+// selectors are applied by each matcher so that they do not split the informers. The
+// informers re-list and resume their watches on their own.
+type InformerCache struct {
+	clientset kubernetes.Interface
+	stop      chan struct{}
+	mu        sync.Mutex
+	informers map[informerKey]*sharedInformer
+}
+
+type informerKey struct {
+	resourceType  ResourceType
+	namespace     string
+	fieldSelector string
+}
+
+// sharedInformer is an informer along with the first list error that retrying cannot fix.
+type sharedInformer struct {
+	cache.SharedIndexInformer
+	failOnce sync.Once
+	failed   chan struct{}
+	err      error
+}
+
+func NewInformerCache(clientset kubernetes.Interface) *InformerCache {
+	return &InformerCache{
+		clientset: clientset,
+		stop:      make(chan struct{}),
+		informers: make(map[informerKey]*sharedInformer),
+	}
+}
+
+// Informer returns the running informer for the resources of resourceType in namespace that
+// match fieldSelector, starting it on first use.
+func (c *InformerCache) Informer(resourceType ResourceType, namespace, fieldSelector string) (*sharedInformer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := informerKey{resourceType, namespace, fieldSelector}
+	if informer, ok := c.informers[key]; ok {
+		return informer, nil
+	}
+	lw, objType, ok := c.listWatch(resourceType, namespace, fieldSelector)
+	if !ok {
+		return nil, fmt.Errorf("no informer for resource type %s", resourceType)
+	}
+	informer := &sharedInformer{failed: make(chan struct{})}
+	lw.ListFunc = informer.listFunc(paginate(lw.ListFunc), c.stop)
+	informer.SharedIndexInformer = cache.NewSharedIndexInformer(lw, objType, 0, cache.Indexers{})
+	c.informers[key] = informer
+	go informer.Run(c.stop)
+	return informer, nil
+}
+
+// Stop stops all informers.
+func (c *InformerCache) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+}
+
+func (c *InformerCache) listWatch(resourceType ResourceType, namespace, fieldSelector string) (*cache.ListWatch, runtime.Object, bool) {
+	switch resourceType {
+	case PodResource:
+		pods := c.clientset.CoreV1().Pods(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return pods.List(options)
+		}, pods.Watch, fieldSelector), &v1.Pod{}, true
+	case JobResource:
+		jobs := c.clientset.BatchV1().Jobs(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return jobs.List(options)
+		}, jobs.Watch, fieldSelector), &batchv1.Job{}, true
+	case CronJobResource:
+		cronJobs := c.clientset.BatchV1beta1().CronJobs(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return cronJobs.List(options)
+		}, cronJobs.Watch, fieldSelector), &batchv1beta1.CronJob{}, true
+	case ReplicaSetResource:
+		replicaSets := c.clientset.AppsV1().ReplicaSets(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return replicaSets.List(options)
+		}, replicaSets.Watch, fieldSelector), &appsv1.ReplicaSet{}, true
+	case DeploymentResource:
+		deployments := c.clientset.AppsV1().Deployments(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return deployments.List(options)
+		}, deployments.Watch, fieldSelector), &appsv1.Deployment{}, true
+	case NodeResource:
+		nodes := c.clientset.CoreV1().Nodes()
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return nodes.List(options)
+		}, nodes.Watch, fieldSelector), &v1.Node{}, true
+	case ConfigMapResource:
+		configMaps := c.clientset.CoreV1().ConfigMaps(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return configMaps.List(options)
+		}, configMaps.Watch, fieldSelector), &v1.ConfigMap{}, true
+	case SecretResource:
+		secrets := c.clientset.CoreV1().Secrets(namespace)
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return secrets.List(options)
+		}, secrets.Watch, fieldSelector), &v1.Secret{}, true
+	case NamespaceResource:
+		namespaces := c.clientset.CoreV1().Namespaces()
+		return newListWatch(func(options metav1.ListOptions) (runtime.Object, error) {
+			return namespaces.List(options)
+		}, namespaces.Watch, fieldSelector), &v1.Namespace{}, true
+	}
+	return nil, nil, false
+}
+
+// newListWatch returns a ListWatch that lists and watches with a typed client's List and
+// Watch, restricted to the objects that match fieldSelector.
+func newListWatch(list cache.ListFunc, watchFunc cache.WatchFunc, fieldSelector string) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return list(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return watchFunc(options)
+		},
+	}
+}
+
+// paginate wraps list to fetch the initial listing in pages of listPageSize objects,
+// so that selectors matching thousands of objects do not need one huge response.
+func paginate(list cache.ListFunc) cache.ListFunc {
+	p := pager.New(func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+		return list(options)
+	})
+	p.PageSize = listPageSize
+	return func(options metav1.ListOptions) (runtime.Object, error) {
+		// the API server serves lists at resource version "0" from its watch cache and
+		// ignores the limit, so read the latest version instead
+		if options.ResourceVersion == "0" {
+			options.ResourceVersion = ""
+		}
+		return p.List(context.Background(), options)
+	}
+}
+
+// listFunc wraps list to back off exponentially between failed calls and to record
+// errors that retrying cannot fix.
+func (i *sharedInformer) listFunc(list cache.ListFunc, stop <-chan struct{}) cache.ListFunc {
+	backoff := newBackoff()
+	failing := false
+	return func(options metav1.ListOptions) (runtime.Object, error) {
+		if failing {
+			select {
+			case <-stop:
+			case <-time.After(backoff.Step()):
+			}
+		}
+		result, err := list(options)
+		if err != nil && !isRetryable(err) {
+			i.fail(err)
+		}
+		if failing = err != nil; !failing {
+			backoff = newBackoff()
+		}
+		return result, err
+	}
+}
+
+func (i *sharedInformer) fail(err error) {
+	i.failOnce.Do(func() {
+		i.err = err
+		close(i.failed)
+	})
+}
+
+// waitForSync blocks until the informer has synced. It returns false if done is closed or ctx
+// is done first, and an error if ctx is done or listing failed in a way that retrying cannot fix.
+func (i *sharedInformer) waitForSync(ctx context.Context, done <-chan struct{}) (bool, error) {
+	for !i.HasSynced() {
+		select {
+		case <-done:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-i.failed:
+			return false, i.err
+		case <-time.After(syncPollPeriod):
+		}
+	}
+	return true, nil
+}
+
+// isRetryable reports whether a failed list or watch call is worth retrying.
+func isRetryable(err error) bool {
+	switch {
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err),
+		apierrors.IsBadRequest(err), apierrors.IsInvalid(err), apierrors.IsNotFound(err):
+		return false
+	}
+	return true
+}
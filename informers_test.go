@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilwait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	testcore "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func init() {
+	newBackoff = func() utilwait.Backoff {
+		return utilwait.Backoff{Duration: 10 * time.Millisecond, Factor: 2, Steps: 3}
+	}
+}
+
+func newTestPod(name, resourceVersion string, ready bool) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "test-ns",
+			ResourceVersion: resourceVersion,
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			Conditions: []v1.PodCondition{
+				v1.PodCondition{Type: v1.PodReady, Status: status},
+			},
+		},
+	}
+}
+
+// watchRecorder hands out a new fake watcher for every watch call and records the
+// resource version each watch was started from.
+type watchRecorder struct {
+	watchers         chan *watch.FakeWatcher
+	resourceVersions chan string
+}
+
+func newWatchRecorder(fake *fakeclientset.Clientset, resource string) *watchRecorder {
+	r := &watchRecorder{
+		watchers:         make(chan *watch.FakeWatcher, 10),
+		resourceVersions: make(chan string, 10),
+	}
+	fake.PrependWatchReactor(resource, func(action testcore.Action) (bool, watch.Interface, error) {
+		watcher := watch.NewFakeWithChanSize(1, false)
+		r.resourceVersions <- action.(testcore.WatchAction).GetWatchRestrictions().ResourceVersion
+		r.watchers <- watcher
+		return true, watcher, nil
+	})
+	return r
+}
+
+func (r *watchRecorder) next(t *testing.T, resourceVersion string) *watch.FakeWatcher {
+	select {
+	case rv := <-r.resourceVersions:
+		if rv != resourceVersion {
+			t.Fatalf("expected watch from resource version %q, got %q", resourceVersion, rv)
+		}
+		return <-r.watchers
+	case <-time.After(time.Second):
+		t.Fatalf("no watch started")
+	}
+	return nil
+}
+
+// expectDone waits for the matcher to finish. Informers wait a second before re-listing,
+// so this allows for a couple of re-lists.
+func expectDone(t *testing.T, matcher Matcher) {
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("matcher did not return after 3s")
+	case <-matcher.Done():
+	}
+}
+
+func TestInformerResumesClosedWatch(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, &v1.PodList{
+			ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+			Items:    []v1.Pod{*newTestPod("pod-1", "9", false)},
+		}, nil
+	})
+	watches := newWatchRecorder(fake, "pods")
+
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	go matcher.Start(context.Background())
+
+	watcher := watches.next(t, "10")
+	watcher.Modify(newTestPod("pod-1", "11", false))
+	// the API server ends the watch, which is resumed from the last resource version seen
+	watcher.Stop()
+
+	watcher = watches.next(t, "11")
+	watcher.Modify(newTestPod("pod-1", "12", true))
+	expectDone(t, matcher)
+}
+
+func TestInformerRelistsWhenGone(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	lists := make(chan struct{}, 10)
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		lists <- struct{}{}
+		if len(lists) == 1 {
+			return true, &v1.PodList{
+				ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+				Items:    []v1.Pod{*newTestPod("pod-1", "9", false)},
+			}, nil
+		}
+		return true, &v1.PodList{
+			ListMeta: metav1.ListMeta{ResourceVersion: "20"},
+			Items:    []v1.Pod{*newTestPod("pod-1", "19", true)},
+		}, nil
+	})
+	watches := newWatchRecorder(fake, "pods")
+
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	go matcher.Start(context.Background())
+
+	watcher := watches.next(t, "10")
+	watcher.Error(&metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusGone,
+		Reason: metav1.StatusReasonGone,
+	})
+	expectDone(t, matcher)
+	if len(lists) != 2 {
+		t.Fatalf("expected 2 lists, got %d", len(lists))
+	}
+}
+
+func TestInformerRetriesListErrors(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	failures := 1
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, apierrors.NewInternalError(http.ErrHandlerTimeout)
+		}
+		return true, &v1.PodList{Items: []v1.Pod{*newTestPod("pod-1", "1", true)}}, nil
+	})
+
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	if err := matcher.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectDone(t, matcher)
+}
+
+func TestInformerForbidden(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", nil)
+	})
+
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	if err := matcher.Start(context.Background()); !apierrors.IsForbidden(err) {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+}
+
+func TestInformerCacheShared(t *testing.T) {
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, &v1.PodList{Items: []v1.Pod{*newTestPod("pod-1", "1", true)}}, nil
+	})
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	for _, selector := range []string{"", "app!=other"} {
+		matcher := NewPodMatcher(informers, StateDescription{
+			Type:           PodResource,
+			Namespace:      "test-ns",
+			LabelSelector:  selector,
+			RequiredStates: []ResourceState{ResourceReady},
+		})
+		if err := matcher.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		expectDone(t, matcher)
+	}
+
+	lists := 0
+	for _, action := range fake.Actions() {
+		if action.GetVerb() == "list" {
+			lists++
+		}
+	}
+	if lists != 1 {
+		t.Fatalf("expected a single list shared by both matchers, got %d", lists)
+	}
+}
+
+func TestInformerMatcherIgnoresStaleNotifications(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	store.Add(newTestPod("pod-1", "2", false))
+
+	matcher := NewPodMatcher(nil, description).(*PodMatcher)
+	matcher.selector = labels.Everything()
+	matcher.store = store
+	matcher.replace()
+
+	// a notification from before the pod stopped being ready arrives after the initial state was loaded
+	matcher.OnAdd(newTestPod("pod-1", "1", true))
+	if state := matcher.States()["test-ns/pod-1"]; state != ResourceRunning {
+		t.Fatalf("stale notification should not change the state, got %s", state)
+	}
+
+	store.Delete(newTestPod("pod-1", "2", false))
+	matcher.OnUpdate(nil, newTestPod("pod-1", "1", true))
+	if _, ok := matcher.States()["test-ns/pod-1"]; ok {
+		t.Fatal("pod deleted from the store should be removed from the state")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	defer func(size int64) { listPageSize = size }(listPageSize)
+	listPageSize = 2
+
+	pods := []v1.Pod{
+		*newTestPod("pod-1", "1", true),
+		*newTestPod("pod-2", "2", true),
+		*newTestPod("pod-3", "3", false),
+	}
+	calls := 0
+	list := paginate(func(options metav1.ListOptions) (runtime.Object, error) {
+		calls++
+		if options.Limit != 2 || options.ResourceVersion != "" {
+			t.Fatalf("unexpected list options: %+v", options)
+		}
+		start := 0
+		if options.Continue != "" {
+			start = 2
+		}
+		page := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10"}}
+		page.Items = pods[start:]
+		if start == 0 {
+			page.Items = pods[:2]
+			page.Continue = "next"
+		}
+		return page, nil
+	})
+
+	result, err := list(metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := meta.ExtractList(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(items) != 3 {
+		t.Fatalf("expected 3 items over 2 pages, got %d items over %d pages", len(items), calls)
+	}
+	if accessor, err := meta.ListAccessor(result); err != nil || accessor.GetResourceVersion() != "10" {
+		t.Fatalf("listing should keep the resource version of the first page: %v", err)
+	}
+}
+
+func TestInformerFieldSelector(t *testing.T) {
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, &v1.PodList{Items: []v1.Pod{*newTestPod("pod-1", "1", true)}}, nil
+	})
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	for _, selector := range []string{"", "metadata.name=pod-1"} {
+		matcher := NewPodMatcher(informers, StateDescription{
+			Type:           PodResource,
+			Namespace:      "test-ns",
+			FieldSelector:  selector,
+			RequiredStates: []ResourceState{ResourceReady},
+		})
+		if err := matcher.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		expectDone(t, matcher)
+	}
+
+	var selectors []string
+	for _, action := range fake.Actions() {
+		switch action := action.(type) {
+		case testcore.ListAction:
+			selectors = append(selectors, action.GetListRestrictions().Fields.String())
+		case testcore.WatchAction:
+			if fields := action.GetWatchRestrictions().Fields.String(); fields != "" && fields != "metadata.name=pod-1" {
+				t.Fatalf("unexpected field selector on watch: %q", fields)
+			}
+		}
+	}
+	if !reflect.DeepEqual(selectors, []string{"", "metadata.name=pod-1"}) {
+		t.Fatalf("expected one list per field selector, got %q", selectors)
+	}
+}
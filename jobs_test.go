@@ -61,7 +61,7 @@ func TestJobComplete(t *testing.T) {
 	})
 	fake.PrependWatchReactor("jobs", testcore.DefaultWatchReactor(watcher, nil))
 
-	matcher := NewJobMatcher(fake, description)
+	matcher := NewJobMatcher(NewInformerCache(fake), description)
 	go matcher.Start(context.Background())
 
 	// simulate real watch
@@ -104,3 +104,97 @@ func TestJobComplete(t *testing.T) {
 	case <-matcher.Done():
 	}
 }
+
+func TestGetJobResourceState(t *testing.T) {
+	minSucceeded := int32(9)
+	tests := []struct {
+		name     string
+		status   batchv1.JobStatus
+		expected ResourceState
+	}{
+		{"new", batchv1.JobStatus{}, resourceWaiting},
+		{"running", batchv1.JobStatus{Active: 2, Succeeded: 8}, ResourceRunning},
+		{"enough succeeded", batchv1.JobStatus{Active: 1, Succeeded: 9}, ResourceSucceeded},
+		{"complete", batchv1.JobStatus{
+			Succeeded:  10,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+		}, ResourceSucceeded},
+		{"complete below minSucceeded", batchv1.JobStatus{
+			Succeeded:  5,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+		}, ResourceComplete},
+		{"deadline exceeded", batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "DeadlineExceeded"}},
+		}, ResourceFailed},
+		{"backoff limit exceeded", batchv1.JobStatus{
+			Failed:     7,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded"}},
+		}, ResourceBackoffLimitExceeded},
+		{"suspended", batchv1.JobStatus{
+			Succeeded:  9,
+			Conditions: []batchv1.JobCondition{{Type: jobSuspended, Status: v1.ConditionTrue}},
+		}, ResourceSuspended},
+	}
+	for _, test := range tests {
+		if state := getJobResourceState(&batchv1.Job{Status: test.status}, &minSucceeded); state != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, state)
+		}
+	}
+}
+
+func TestJobValidatorMinSucceeded(t *testing.T) {
+	minSucceeded := int32(9)
+	description := StateDescription{
+		Type:           JobResource,
+		RequiredStates: []ResourceState{ResourceComplete},
+		MinSucceeded:   &minSucceeded,
+	}
+	if err := NewJobValidator().Validate(context.Background(), description); err == nil || err.Error() != ErrMinSucceededWithoutSucceeded(description).Error() {
+		t.Fatalf("expected minSucceeded without Succeeded to be rejected, got %v", err)
+	}
+	description.RequiredStates = []ResourceState{ResourceSucceeded}
+	if err := NewJobValidator().Validate(context.Background(), description); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobMatcherIgnoresStaleJobs(t *testing.T) {
+	complete := []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	stale := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "seeder-old",
+			Namespace:         "test-ns",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-7 * 24 * time.Hour)),
+		},
+		Status: batchv1.JobStatus{Conditions: complete},
+	}
+	fresh := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "seeder-new",
+			Namespace:         "test-ns",
+			CreationTimestamp: metav1.NewTime(time.Now()),
+		},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+	description := StateDescription{
+		Type:           JobResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceComplete},
+		CreatedAfter:   "1h",
+	}
+	descriptions := []StateDescription{description}
+	if err := ResolveCreatedAfter(nil, descriptions, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	fake := fakeclientset.NewSimpleClientset(stale, fresh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	matcher := NewJobMatcher(NewInformerCache(fake), descriptions[0])
+	if err := matcher.Start(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("the stale job should not satisfy the description, got %v", err)
+	}
+	if states := matcher.States(); len(states) != 1 || states["test-ns/seeder-new"] != ResourceRunning {
+		t.Fatalf("expected only the fresh job in the state, got %v", states)
+	}
+}
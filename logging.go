@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LogLevelEnv sets the default for --log-level.
+	LogLevelEnv = "KUBEWAIT_LOG_LEVEL"
+	// LogFormatEnv sets the default for --log-format.
+	LogFormatEnv = "KUBEWAIT_LOG_FORMAT"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// configureLogging sets the level and format of the standard logger.
+func configureLogging(level, format string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case LogFormatText:
+		log.SetFormatter(&log.TextFormatter{})
+	case LogFormatJSON:
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("not a valid log format: %q", format)
+	}
+	log.SetLevel(lvl)
+	return nil
+}
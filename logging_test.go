@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	defer log.SetFormatter(&log.TextFormatter{})
+	defer log.SetLevel(log.DebugLevel)
+
+	if err := configureLogging("warning", LogFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if log.GetLevel() != log.WarnLevel {
+		t.Fatalf("expected level warning, got %v", log.GetLevel())
+	}
+	if _, ok := log.StandardLogger().Formatter.(*log.JSONFormatter); !ok {
+		t.Fatalf("expected JSON formatter, got %T", log.StandardLogger().Formatter)
+	}
+
+	if err := configureLogging("loud", LogFormatText); err == nil {
+		t.Fatal("invalid log level should fail")
+	}
+	if err := configureLogging("info", "xml"); err == nil {
+		t.Fatal("invalid log format should fail")
+	}
+}
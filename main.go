@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
@@ -11,24 +16,92 @@ import (
 
 const DefaultEnv = "KUBEWAIT"
 
-func init() {
-	if env, _ := os.LookupEnv("ENV"); env == "DEBUG" {
-		log.SetLevel(log.DebugLevel)
-	}
-}
+// TimeoutEnv optionally limits how long kubewait waits for the descriptions to match, e.g. "10m".
+const TimeoutEnv = "KUBEWAIT_TIMEOUT"
+
+const (
+	exitNotMatched = 1
+	exitUsage      = 2
+	// a signal exits with exitSignalBase plus the signal number, as a shell would report it
+	exitSignalBase = 128
+)
 
 func main() {
+	logLevel := flag.String("log-level", envOrDefault(LogLevelEnv, "info"), "log level: debug, info, warning or error")
+	logFormat := flag.String("log-format", envOrDefault(LogFormatEnv, LogFormatText), "log format: text or json")
+	flag.Parse()
+	if err := configureLogging(*logLevel, *logFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
 	config, err := rest.InClusterConfig()
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		panic(err)
 	}
-	log.Debugf("loaded kubernetes clientset\n")
+	log.Debug("loaded kubernetes clientset")
 	descriptions, err := GetStateDescriptionsFromEnv(DefaultEnv)
 	if err != nil {
 		panic(err)
 	}
-	log.Debugf("loaded state descriptions: %v\n", descriptions)
-	ctx := context.Background()
-	wait(ctx, clientset, descriptions)
+	if err := SetDefaultNamespace(descriptions); err != nil {
+		panic(err)
+	}
+	if err := ResolveCreatedAfter(clientset, descriptions, time.Now()); err != nil {
+		panic(err)
+	}
+	for _, description := range descriptions {
+		description.Logger().WithField("requiredStates", description.RequiredStates).Debug("loaded state description")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := make(chan syscall.Signal, 1)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		// a second signal gets the default behaviour, so a hung shutdown can still be interrupted
+		signal.Stop(signals)
+		log.WithField("signal", sig).Warn("received signal, stopping")
+		interrupted <- sig.(syscall.Signal)
+		cancel()
+	}()
+	if value, ok := os.LookupEnv(TimeoutEnv); ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			panic(err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	progressInterval := DefaultProgressInterval
+	if value, ok := os.LookupEnv(ProgressIntervalEnv); ok {
+		progressInterval, err = time.ParseDuration(value)
+		if err != nil {
+			panic(err)
+		}
+	}
+	reporter := NewEventReporterFromEnv(clientset)
+	results, err := wait(ctx, clientset, reporter, progressInterval, descriptions)
+	reporter.Shutdown()
+	terminationLog := DefaultTerminationLog
+	if path, ok := os.LookupEnv(TerminationLogEnv); ok {
+		terminationLog = path
+	}
+	if werr := WriteTerminationMessage(terminationLog, results, err); werr != nil {
+		log.WithField("path", terminationLog).Warnf("could not write termination message: %v", werr)
+	}
+	if err == nil {
+		return
+	}
+	logUnmatched(results)
+	log.Error(err)
+	select {
+	case sig := <-interrupted:
+		os.Exit(exitSignalBase + int(sig))
+	default:
+		os.Exit(exitNotMatched)
+	}
 }
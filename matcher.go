@@ -1,10 +1,336 @@
 package main
 
-import "context"
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	log "github.com/sirupsen/logrus"
+	funk "github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Matcher waits for the resources of a StateDescription to reach one of the required states.
 type Matcher interface {
+	// Start blocks until the description is matched, the matcher is stopped or ctx is done,
+	// in which case it returns the context's error. It returns a *FailedStateError as soon as
+	// a resource reaches one of the description's FailOn states.
 	Start(context.Context) error
-	Done() <-chan bool
+	// Done is closed once the description is matched or the matcher is stopped.
+	Done() <-chan struct{}
+	// Stop makes Start return. It is safe to call more than once.
 	Stop(context.Context) error
+	// States returns a snapshot of the state of every resource seen by the matcher, keyed by
+	// namespace/name.
+	States() map[string]ResourceState
+	// Reasons returns, keyed by namespace/name, why resources seen by the matcher are not ready.
+	Reasons() map[string]string
+}
+
+// informerMatcher implements Matcher on top of a shared informer. Resource specific
+// matchers embed it and provide evaluate.
+type informerMatcher struct {
+	informers   *InformerCache
+	description StateDescription
+	// evaluate returns the state of a resource and the reason it is in that state.
+	evaluate func(obj interface{}) (ResourceState, string)
+	// ignore optionally leaves resources out of the state whatever the description selects.
+	ignore   func(obj interface{}) bool
+	selector labels.Selector
+	// namespaceSelector is nil unless the description selects namespaces by label, in which
+	// case namespaces holds the cluster's namespaces.
+	namespaceSelector labels.Selector
+	namespaces        cache.Store
+	// owners holds the stores of the owners followed when selecting by owner, by type.
+	owners map[ResourceType]cache.Store
+	// store is the informer's cache, which is the source of truth for the state of a resource.
+	store    cache.Store
+	changed  chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+	// mu guards states and reasons, which are written by the informers and read through States
+	// and Reasons. It is held while reading the store so that concurrent writers agree on the
+	// order of the versions they read.
+	mu      sync.RWMutex
+	states  map[string]ResourceState
+	reasons map[string]string
+}
+
+func newInformerMatcher(informers *InformerCache, description StateDescription, evaluate func(interface{}) (ResourceState, string)) *informerMatcher {
+	return &informerMatcher{
+		informers:   informers,
+		description: description,
+		evaluate:    evaluate,
+		changed:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		states:      make(map[string]ResourceState),
+		reasons:     make(map[string]string),
+	}
+}
+
+func (m *informerMatcher) Start(ctx context.Context) error {
+	selector, err := labels.Parse(m.description.LabelSelector)
+	if err != nil {
+		return err
+	}
+	m.selector = selector
+	informer, err := m.informers.Informer(m.description.Type, m.description.InformerNamespace(), m.description.ServerFieldSelector())
+	if err != nil {
+		return err
+	}
+
+	logger := m.description.Logger()
+	logger.Debug("fetching initial context")
+	if m.description.NamespaceSelector != "" {
+		if synced, err := m.watchNamespaces(ctx); !synced {
+			return err
+		}
+	}
+	if m.description.selectsByOwner() {
+		if synced, err := m.watchOwners(ctx); !synced {
+			return err
+		}
+	}
+	m.mu.Lock()
+	m.store = informer.GetStore()
+	m.mu.Unlock()
+	informer.AddEventHandler(m)
+	// The informer lists and then watches from the resource version of the list, so no
+	// change between the two is lost. Once it has synced its store holds the complete
+	// initial state, which replaces whatever notifications the matcher has seen so far.
+	if synced, err := informer.waitForSync(ctx, m.done); !synced {
+		return err
+	}
+	m.replace()
+	logger.Debug("fetched context")
+
+	for !m.matched() {
+		if err := m.failed(); err != nil {
+			logger.WithField("resource", err.Resource).Warn("resource reached a failOn state")
+			return err
+		}
+		select {
+		case <-m.changed:
+		case <-m.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	logger.Info("state description matched by cluster")
+	m.close()
+	return nil
+}
+
+// watchNamespaces starts following the labels of namespaces for the namespace selector.
+func (m *informerMatcher) watchNamespaces(ctx context.Context) (bool, error) {
+	selector, err := labels.Parse(m.description.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	m.namespaceSelector = selector
+	informer, err := m.follow(NamespaceResource, metav1.NamespaceAll)
+	if err != nil {
+		return false, err
+	}
+	m.namespaces = informer.GetStore()
+	return informer.waitForSync(ctx, m.done)
+}
+
+// follow returns the informer of other resources that the state of the resources depends
+// on. Whenever one of its objects changes the resources are evaluated again.
+func (m *informerMatcher) follow(resourceType ResourceType, namespace string) (*sharedInformer, error) {
+	informer, err := m.informers.Informer(resourceType, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { m.replace() },
+		UpdateFunc: func(interface{}, interface{}) { m.replace() },
+		DeleteFunc: func(interface{}) { m.replace() },
+	})
+	return informer, nil
+}
+
+func (m *informerMatcher) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *informerMatcher) Stop(ctx context.Context) error {
+	m.close()
+	return nil
+}
+
+func (m *informerMatcher) close() {
+	m.doneOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *informerMatcher) States() map[string]ResourceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make(map[string]ResourceState, len(m.states))
+	for key, state := range m.states {
+		states[key] = state
+	}
+	return states
+}
+
+func (m *informerMatcher) Reasons() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	reasons := make(map[string]string, len(m.reasons))
+	for key, reason := range m.reasons {
+		reasons[key] = reason
+	}
+	return reasons
+}
+
+// OnAdd, OnUpdate and OnDelete implement cache.ResourceEventHandler. Notifications are
+// delivered asynchronously and may lag behind the store, so they only signal which resource
+// changed and its current version is read back from the store. Otherwise a stale notification
+// processed after replace could overwrite a newer state and cause a false match.
+func (m *informerMatcher) OnAdd(obj interface{}) {
+	m.refresh(obj, "added to state")
+}
+
+func (m *informerMatcher) OnUpdate(oldObj, newObj interface{}) {
+	m.refresh(newObj, "updated state")
+}
+
+func (m *informerMatcher) OnDelete(obj interface{}) {
+	m.refresh(obj, "updated state")
+}
+
+// refresh updates the state of obj from the informer's store.
+func (m *informerMatcher) refresh(obj interface{}, message string) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	current, exists, err := m.store.GetByKey(key)
+	if err != nil {
+		m.mu.Unlock()
+		return
+	}
+	if !exists || !m.matches(current) {
+		// the resource is gone or no longer matches, e.g. because its labels changed
+		_, tracked := m.states[key]
+		delete(m.states, key)
+		delete(m.reasons, key)
+		m.mu.Unlock()
+		if tracked {
+			m.description.Logger().WithField("resource", key).Debug(m.kind() + " removed from state")
+			m.notify()
+		}
+		return
+	}
+	state, reason := m.evaluate(current)
+	m.states[key], m.reasons[key] = state, reason
+	m.mu.Unlock()
+	m.description.Logger().WithFields(log.Fields{
+		"resource": key,
+		"state":    state,
+		"reason":   reason,
+	}).Debug(m.kind() + " " + message)
+	m.notify()
+}
+
+// replace resets the state to the objects in the informer's store that match the description.
+func (m *informerMatcher) replace() {
+	m.mu.Lock()
+	if m.store == nil {
+		// the resources have not been listed yet
+		m.mu.Unlock()
+		return
+	}
+	states := make(map[string]ResourceState)
+	reasons := make(map[string]string)
+	for _, obj := range m.store.List() {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil || !m.matches(obj) {
+			continue
+		}
+		states[key], reasons[key] = m.evaluate(obj)
+	}
+	m.states, m.reasons = states, reasons
+	m.mu.Unlock()
+	m.notify()
 }
 
+// matches reports whether a resource is selected by the description. Only the field selector
+// is applied by the API server, everything else is checked here.
+func (m *informerMatcher) matches(obj interface{}) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil || m.ignore != nil && m.ignore(obj) {
+		return false
+	}
+	// creation timestamps only have a precision of seconds
+	if created := accessor.GetCreationTimestamp(); created.Time.Before(m.description.createdAfter.Truncate(time.Second)) {
+		return false
+	}
+	return m.selector.Matches(labels.Set(accessor.GetLabels())) &&
+		m.description.MatchesName(accessor.GetName()) &&
+		m.description.MatchesVersion(accessor.GetLabels(), accessor.GetAnnotations()) &&
+		m.matchesNamespace(accessor.GetNamespace()) &&
+		(!m.description.selectsByOwner() || m.matchesOwner(accessor))
+}
+
+func (m *informerMatcher) matchesNamespace(name string) bool {
+	if m.namespaceSelector == nil {
+		return true
+	}
+	obj, exists, err := m.namespaces.GetByKey(name)
+	if err != nil || !exists {
+		return false
+	}
+	accessor, err := meta.Accessor(obj)
+	return err == nil && m.namespaceSelector.Matches(labels.Set(accessor.GetLabels()))
+}
+
+// notify wakes up Start to re-evaluate the match.
+func (m *informerMatcher) notify() {
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (m *informerMatcher) matched() bool {
+	return m.description.MatchStates(m.States())
+}
+
+// failed returns an error for the first resource in one of the description's FailOn states.
+func (m *informerMatcher) failed() *FailedStateError {
+	if len(m.description.FailOn) == 0 {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.states))
+	for key := range m.states {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	failOn := m.description.expandStates(m.description.FailOn)
+	for _, key := range keys {
+		if state := m.states[key]; funk.Contains(failOn, state) {
+			return &FailedStateError{Resource: key, State: state, Reason: m.reasons[key]}
+		}
+	}
+	return nil
+}
+
+func (m *informerMatcher) kind() string {
+	return strings.ToLower(string(m.description.Type))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	watch "k8s.io/apimachinery/pkg/watch"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	testcore "k8s.io/client-go/testing"
+)
+
+// startMatcher runs Start in the background and returns a channel with its result.
+func startMatcher(ctx context.Context, matcher Matcher) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- matcher.Start(ctx)
+	}()
+	return result
+}
+
+func expectStartReturns(t *testing.T, result <-chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return")
+	}
+	return nil
+}
+
+func TestMatcherStartReturnsOnMatch(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	watcher := watch.NewFakeWithChanSize(1, false)
+	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watcher, nil))
+
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	result := startMatcher(context.Background(), matcher)
+	watcher.Add(newTestPod("pod-1", "1", true))
+
+	if err := expectStartReturns(t, result); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-matcher.Done():
+	default:
+		t.Fatal("Done should be closed once Start returns after a match")
+	}
+}
+
+func TestMatcherStartHonoursContext(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watch.NewFake(), nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	result := startMatcher(ctx, matcher)
+	cancel()
+
+	if err := expectStartReturns(t, result); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestMatcherStopIdempotent(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watch.NewFake(), nil))
+
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
+	result := startMatcher(context.Background(), matcher)
+	for i := 0; i < 3; i++ {
+		if err := matcher.Stop(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := expectStartReturns(t, result); err != nil {
+		t.Fatal(err)
+	}
+	<-matcher.Done()
+}
+
+func TestMatcherNamespaces(t *testing.T) {
+	shared := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tier": "shared"}}}
+	other := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}}
+	ready := newTestPod("db", "1", true)
+	ready.Namespace = "tenant-a"
+	waiting := newTestPod("db", "2", false)
+	waiting.Namespace = "tenant-b"
+	fake := fakeclientset.NewSimpleClientset(shared, other, ready, waiting)
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	selected := NewPodMatcher(informers, StateDescription{
+		Type:              PodResource,
+		NamespaceSelector: "tier=shared",
+		RequiredStates:    []ResourceState{ResourceReady},
+	})
+	if err := expectStartReturns(t, startMatcher(context.Background(), selected)); err != nil {
+		t.Fatal(err)
+	}
+	if states := selected.States(); !reflect.DeepEqual(states, map[string]ResourceState{"tenant-a/db": ResourceReady}) {
+		t.Fatalf("expected only the pod in the selected namespace, got %v", states)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	all := NewPodMatcher(informers, StateDescription{
+		Type:           PodResource,
+		Namespace:      AllNamespaces,
+		RequiredStates: []ResourceState{ResourceReady},
+	})
+	if err := expectStartReturns(t, startMatcher(ctx, all)); err != context.DeadlineExceeded {
+		t.Fatalf("expected the waiting pod to block the match, got %v", err)
+	}
+	expected := map[string]ResourceState{"tenant-a/db": ResourceReady, "tenant-b/db": ResourceRunning}
+	if states := all.States(); !reflect.DeepEqual(states, expected) {
+		t.Fatalf("expected pods with the same name to be kept apart, got %v", states)
+	}
+}
+
+func TestMatcherFailOn(t *testing.T) {
+	crashing := newTestPod("pod-1", "1", false)
+	crashing.Status.ContainerStatuses = []v1.ContainerStatus{
+		v1.ContainerStatus{
+			Name:  "app",
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		},
+	}
+	fake := fakeclientset.NewSimpleClientset(crashing)
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	matcher := NewPodMatcher(informers, StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+		FailOn:         []ResourceState{ResourceCrashLoopBackOff},
+	})
+	err := expectStartReturns(t, startMatcher(context.Background(), matcher))
+	failed, ok := err.(*FailedStateError)
+	if !ok || failed.Resource != "test-ns/pod-1" || failed.State != ResourceCrashLoopBackOff {
+		t.Fatalf("expected pod-1 to fail the match, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+)
+
+var namespacePermittedStates = []ResourceState{ResourceActive, ResourceTerminating}
+
+type NamespaceMatcher struct {
+	*informerMatcher
+}
+
+type NamespaceValidator struct {
+	BaseValidator
+}
+
+func (v *NamespaceValidator) Validate(ctx context.Context, description StateDescription) error {
+	err := v.BaseValidator.Validate(ctx, description)
+	if err != nil {
+		return err
+	}
+	return validateStates(description, namespacePermittedStates)
+}
+
+func NewNamespaceValidator() Validator {
+	return &NamespaceValidator{}
+}
+
+func NewNamespaceMatcher(informers *InformerCache, description StateDescription) Matcher {
+	m := &NamespaceMatcher{}
+	m.informerMatcher = newInformerMatcher(informers, description, m.evaluate)
+	return m
+}
+
+func (m *NamespaceMatcher) evaluate(obj interface{}) (ResourceState, string) {
+	namespace := obj.(*v1.Namespace)
+	switch namespace.Status.Phase {
+	case v1.NamespaceActive:
+		return ResourceActive, ""
+	case v1.NamespaceTerminating:
+		return ResourceTerminating, "namespace is being deleted"
+	}
+	return resourceWaiting, ""
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+)
+
+var nodePermittedStates = []ResourceState{
+	ResourceReady, ResourceSchedulable, ResourceCordoned,
+	ResourceMemoryPressure, ResourceDiskPressure, ResourcePIDPressure, ResourceNetworkUnavailable,
+}
+
+// nodePressureStates maps the node conditions that hold a ready node back to their states.
+var nodePressureStates = []struct {
+	condition v1.NodeConditionType
+	state     ResourceState
+}{
+	{v1.NodeNetworkUnavailable, ResourceNetworkUnavailable},
+	{v1.NodeMemoryPressure, ResourceMemoryPressure},
+	{v1.NodeDiskPressure, ResourceDiskPressure},
+	{v1.NodePIDPressure, ResourcePIDPressure},
+}
+
+// nodeReadyStates are the states of a ready node, which Ready stands for in a node description.
+var nodeReadyStates = []ResourceState{
+	ResourceSchedulable, ResourceCordoned,
+	ResourceMemoryPressure, ResourceDiskPressure, ResourcePIDPressure, ResourceNetworkUnavailable,
+}
+
+type NodeMatcher struct {
+	*informerMatcher
+}
+
+type NodeValidator struct {
+	BaseValidator
+}
+
+func (v *NodeValidator) Validate(ctx context.Context, description StateDescription) error {
+	err := v.BaseValidator.Validate(ctx, description)
+	if err != nil {
+		return err
+	}
+	return validateStates(description, nodePermittedStates)
+}
+
+func NewNodeValidator() Validator {
+	return &NodeValidator{}
+}
+
+func NewNodeMatcher(informers *InformerCache, description StateDescription) Matcher {
+	m := &NodeMatcher{}
+	m.informerMatcher = newInformerMatcher(informers, description, m.evaluate)
+	return m
+}
+
+func (m *NodeMatcher) evaluate(obj interface{}) (ResourceState, string) {
+	node := obj.(*v1.Node)
+	return getNodeResourceState(node), explainNode(node)
+}
+
+// getNodeResourceState returns waiting for nodes that are not ready, the first pressure
+// condition of a ready node, and Schedulable or Cordoned for the other ready nodes.
+func getNodeResourceState(node *v1.Node) ResourceState {
+	if nodeCondition(node, v1.NodeReady) != v1.ConditionTrue {
+		return resourceWaiting
+	}
+	for _, pressure := range nodePressureStates {
+		if nodeCondition(node, pressure.condition) == v1.ConditionTrue {
+			return pressure.state
+		}
+	}
+	if node.Spec.Unschedulable {
+		return ResourceCordoned
+	}
+	return ResourceSchedulable
+}
+
+// nodeCondition returns the status of the node condition of the given type, or an empty
+// status if the node does not report it.
+func nodeCondition(node *v1.Node, conditionType v1.NodeConditionType) v1.ConditionStatus {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return ""
+}
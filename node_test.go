@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestNode(name string, unschedulable bool, conditions ...v1.NodeCondition) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": "gpu"}},
+		Spec:       v1.NodeSpec{Unschedulable: unschedulable},
+		Status:     v1.NodeStatus{Conditions: conditions},
+	}
+}
+
+func TestGetNodeResourceState(t *testing.T) {
+	ready := v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue}
+	tests := []struct {
+		node     *v1.Node
+		expected ResourceState
+		reason   string
+	}{
+		{newTestNode("new", false), resourceWaiting, "not ready"},
+		{newTestNode("down", false, v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionUnknown, Reason: "NodeStatusUnknown"}), resourceWaiting, "not ready: NodeStatusUnknown"},
+		{newTestNode("full", false, ready, v1.NodeCondition{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue}), ResourceDiskPressure, "DiskPressure"},
+		{newTestNode("cordoned", true, ready), ResourceCordoned, ""},
+		{newTestNode("ok", false, ready), ResourceSchedulable, ""},
+	}
+	for _, test := range tests {
+		if state := getNodeResourceState(test.node); state != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.node.Name, test.expected, state)
+		}
+		if reason := explainNode(test.node); reason != test.reason {
+			t.Errorf("%s: expected reason %q, got %q", test.node.Name, test.reason, reason)
+		}
+	}
+}
+
+func TestNodeMatcherMinCount(t *testing.T) {
+	ready := v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue}
+	fake := fakeclientset.NewSimpleClientset(
+		newTestNode("gpu-1", false, ready),
+		newTestNode("gpu-2", false, ready),
+		newTestNode("gpu-3", false),
+	)
+	minCount := int32(2)
+	matcher := NewNodeMatcher(NewInformerCache(fake), StateDescription{
+		Type:           NodeResource,
+		LabelSelector:  "pool=gpu",
+		RequiredStates: []ResourceState{ResourceSchedulable},
+		MinCount:       &minCount,
+	})
+	if err := expectStartReturns(t, startMatcher(context.Background(), matcher)); err != nil {
+		t.Fatal(err)
+	}
+	if states := matcher.States(); len(states) != 3 || states["gpu-3"] != resourceWaiting {
+		t.Fatalf("expected all three nodes keyed by name, got %v", states)
+	}
+}
+
+func TestNodeReadyMatchesReadyStates(t *testing.T) {
+	description := StateDescription{Type: NodeResource, RequiredStates: []ResourceState{ResourceReady}}
+	if err := NewNodeValidator().Validate(context.Background(), description); err != nil {
+		t.Fatalf("expected Ready to be a valid node state, got %v", err)
+	}
+	states := map[string]ResourceState{"ok": ResourceSchedulable, "cordoned": ResourceCordoned, "full": ResourceDiskPressure}
+	if !description.MatchStates(states) {
+		t.Errorf("expected Ready to match %v", states)
+	}
+	states["new"] = resourceWaiting
+	if description.MatchStates(states) {
+		t.Errorf("expected Ready not to match %v", states)
+	}
+	minCount := int32(3)
+	description.MinCount = &minCount
+	if !description.MatchStates(states) {
+		t.Errorf("expected three ready nodes to match minCount 3 in %v", states)
+	}
+}
+
+func TestValidatorMinCount(t *testing.T) {
+	for _, minCount := range []int32{0, -1} {
+		description := StateDescription{
+			Type:           NodeResource,
+			RequiredStates: []ResourceState{ResourceSchedulable},
+			MinCount:       &minCount,
+		}
+		if err := NewNodeValidator().Validate(context.Background(), description); err == nil || err.Error() != ErrInvalidMinCount(description).Error() {
+			t.Errorf("minCount %d: expected %v, got %v", minCount, ErrInvalidMinCount(description), err)
+		}
+	}
+}
+
+func TestValidatorClusterScoped(t *testing.T) {
+	tests := []struct {
+		description StateDescription
+		field       string
+	}{
+		{StateDescription{Type: NodeResource, Namespace: "default", RequiredStates: []ResourceState{ResourceSchedulable}}, "namespace"},
+		{StateDescription{Type: NodeResource, NamespaceSelector: "tier=shared", RequiredStates: []ResourceState{ResourceSchedulable}}, "namespaceSelector"},
+		{StateDescription{Type: NamespaceResource, NamespaceSelector: "tier=shared", RequiredStates: []ResourceState{ResourceActive}}, "namespaceSelector"},
+	}
+	for _, test := range tests {
+		validator, _ := getValidator(nil, test.description)
+		expected := ErrFieldNotValidForResourceType(test.description, test.field)
+		if err := validator.Validate(context.Background(), test.description); err == nil || err.Error() != expected.Error() {
+			t.Errorf("%v: expected %v, got %v", test.description, expected, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// HelmReleaseAnnotation names the Helm release that installed a resource.
+	HelmReleaseAnnotation = "meta.helm.sh/release-name"
+	// HelmRevisionLabel holds the revision of the Helm release that last changed a resource,
+	// for charts that set it.
+	HelmRevisionLabel = "helm.sh/revision"
+	// deploymentRevisionAnnotation holds the revision of a deployment and of its replica sets.
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// maxOwnerDepth bounds the walk up the controller references, e.g. pod, replica set, deployment.
+const maxOwnerDepth = 5
+
+// ownerTypes are the owners whose own owners are looked up, by the type of resource they own.
+var ownerTypes = map[ResourceType][]ResourceType{
+	PodResource:     {ReplicaSetResource, DeploymentResource, JobResource, CronJobResource},
+	JobResource:     {CronJobResource},
+	CronJobResource: {},
+}
+
+// selectsByOwner reports whether the description selects resources by their owners.
+func (d StateDescription) selectsByOwner() bool {
+	return d.OwnerKind != "" || d.OwnerName != "" || d.HelmRelease != "" || d.HelmRevision != ""
+}
+
+// watchOwners starts following the owners of the resources, so that an owner chain can be
+// walked from the informers' stores.
+func (m *informerMatcher) watchOwners(ctx context.Context) (bool, error) {
+	owners := make(map[ResourceType]cache.Store)
+	var informers []*sharedInformer
+	for _, resourceType := range ownerTypes[m.description.Type] {
+		informer, err := m.follow(resourceType, m.description.InformerNamespace())
+		if err != nil {
+			return false, err
+		}
+		owners[resourceType] = informer.GetStore()
+		informers = append(informers, informer)
+	}
+	m.owners = owners
+	// the informers list in parallel, so waiting for each in turn takes as long as the slowest
+	for _, informer := range informers {
+		if synced, err := informer.waitForSync(ctx, m.done); !synced {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchesOwner reports whether the owner chain of a resource satisfies the description's
+// owner and Helm fields. A pod of a deployment only matches the deployment while its replica
+// set is the current revision, so pods of an earlier rollout do not.
+func (m *informerMatcher) matchesOwner(obj metav1.Object) bool {
+	ownerMatched := m.description.OwnerKind == "" && m.description.OwnerName == ""
+	helmMatched := m.description.HelmRelease == "" && m.description.HelmRevision == ""
+	helmMatched = helmMatched || m.matchesHelm(obj)
+	for depth := 0; depth < maxOwnerDepth; depth++ {
+		ref := metav1.GetControllerOf(obj)
+		if ref == nil {
+			break
+		}
+		owner := m.lookupOwner(obj.GetNamespace(), ref)
+		if owner != nil && !isCurrentRevision(obj, owner, ref.Kind) {
+			return false
+		}
+		if (m.description.OwnerKind == "" || m.description.OwnerKind == ref.Kind) &&
+			(m.description.OwnerName == "" || m.description.OwnerName == ref.Name) {
+			ownerMatched = true
+		}
+		if owner == nil {
+			break
+		}
+		helmMatched = helmMatched || m.matchesHelm(owner)
+		obj = owner
+	}
+	return ownerMatched && helmMatched
+}
+
+// lookupOwner returns the owner referenced by ref from the stores, or nil if it is not a
+// followed type or cannot be found.
+func (m *informerMatcher) lookupOwner(namespace string, ref *metav1.OwnerReference) metav1.Object {
+	store, ok := m.owners[ResourceType(ref.Kind)]
+	if !ok {
+		return nil
+	}
+	obj, exists, err := store.GetByKey(namespace + "/" + ref.Name)
+	if err != nil || !exists {
+		return nil
+	}
+	owner, err := meta.Accessor(obj)
+	if err != nil || owner.GetUID() != ref.UID {
+		return nil
+	}
+	return owner
+}
+
+func (m *informerMatcher) matchesHelm(obj metav1.Object) bool {
+	if release := m.description.HelmRelease; release != "" && obj.GetAnnotations()[HelmReleaseAnnotation] != release {
+		return false
+	}
+	if revision := m.description.HelmRevision; revision != "" && obj.GetLabels()[HelmRevisionLabel] != revision {
+		return false
+	}
+	return true
+}
+
+// isCurrentRevision reports whether a replica set is the current revision of the deployment
+// that owns it. It returns true for any other kind of owner.
+func isCurrentRevision(obj, owner metav1.Object, kind string) bool {
+	if kind != string(DeploymentResource) {
+		return true
+	}
+	revision, ok := owner.GetAnnotations()[deploymentRevisionAnnotation]
+	return !ok || obj.GetAnnotations()[deploymentRevisionAnnotation] == revision
+}
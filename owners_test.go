@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{
+		metav1.OwnerReference{Kind: kind, Name: name, UID: types.UID(name + "-uid"), Controller: &controller},
+	}
+}
+
+func TestMatcherOwners(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "test-ns",
+			UID:       "api-uid",
+			Annotations: map[string]string{
+				deploymentRevisionAnnotation: "2",
+				HelmReleaseAnnotation:        "backend",
+			},
+		},
+	}
+	replicaSets := []*appsv1.ReplicaSet{}
+	for _, revision := range []string{"1", "2"} {
+		replicaSets = append(replicaSets, &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "api-" + revision,
+				Namespace:       "test-ns",
+				UID:             types.UID("api-" + revision + "-uid"),
+				Annotations:     map[string]string{deploymentRevisionAnnotation: revision},
+				OwnerReferences: controlledBy("Deployment", "api"),
+			},
+		})
+	}
+	old := newTestPod("api-1-abcde", "1", true)
+	old.OwnerReferences = controlledBy("ReplicaSet", "api-1")
+	current := newTestPod("api-2-fghij", "2", false)
+	current.OwnerReferences = controlledBy("ReplicaSet", "api-2")
+	unrelated := newTestPod("worker", "3", true)
+	fake := fakeclientset.NewSimpleClientset(deployment, replicaSets[0], replicaSets[1], old, current, unrelated)
+	informers := NewInformerCache(fake)
+	defer informers.Stop()
+
+	descriptions := []StateDescription{
+		{OwnerKind: "Deployment", OwnerName: "api"},
+		{HelmRelease: "backend"},
+	}
+	for _, description := range descriptions {
+		description.Type = PodResource
+		description.Namespace = "test-ns"
+		description.RequiredStates = []ResourceState{ResourceReady}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		matcher := NewPodMatcher(informers, description)
+		if err := matcher.Start(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("%v: only the pod of the current revision should count, got %v", description, err)
+		}
+		cancel()
+		expected := map[string]ResourceState{"test-ns/api-2-fghij": ResourceRunning}
+		if states := matcher.States(); !reflect.DeepEqual(states, expected) {
+			t.Fatalf("%v: expected %v, got %v", description, expected, states)
+		}
+	}
+}
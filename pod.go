@@ -2,26 +2,22 @@ package main
 
 import (
 	"context"
+	"path"
+	"strings"
 
-	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
-
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	watch "k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/kubernetes"
-
-	funk "github.com/thoas/go-funk"
 )
 
-var podPermittedStates = []ResourceState{ResourceReady, ResourceSucceeded, ResourceFailed}
+var podPermittedStates = []ResourceState{
+	ResourceReady, ResourceSucceeded, ResourceFailed,
+	ResourceRunning, ResourceScheduled, ResourceInitialized, ResourceContainersReady,
+	ResourceCrashLoopBackOff, ResourceImagePullBackOff, ResourceUnschedulable,
+	ResourceRestartLimitExceeded,
+}
 
 // PodMatcher
 type PodMatcher struct {
-	clientset   kubernetes.Interface
-	description StateDescription
-	podstate    map[string]ResourceState
-	watcher     watch.Interface
-	done        chan bool
+	*informerMatcher
 }
 
 // PodValidator
@@ -34,145 +30,167 @@ func (p *PodValidator) Validate(ctx context.Context, description StateDescriptio
 	if err != nil {
 		return err
 	}
-
-	for _, requiredState := range description.RequiredStates {
-		if !funk.Contains(podPermittedStates, requiredState) {
-			return ErrStateNotValidForResourceType(description, requiredState)
-		}
-	}
-	return nil
+	return validateStates(description, podPermittedStates)
 }
 
 func NewPodValidator() Validator {
 	return &PodValidator{}
 }
 
-func NewPodMatcher(clientset kubernetes.Interface, description StateDescription) Matcher {
-	return &PodMatcher{
-		clientset:   clientset,
-		description: description,
-		done:        make(chan bool, 1),
-		podstate:    make(map[string]ResourceState),
+func NewPodMatcher(informers *InformerCache, description StateDescription) Matcher {
+	p := &PodMatcher{}
+	p.informerMatcher = newInformerMatcher(informers, description, p.evaluate)
+	p.ignore = func(obj interface{}) bool {
+		return isTerminating(obj) || !p.runsImage(obj.(*v1.Pod))
 	}
+	return p
 }
 
-func (p *PodMatcher) Start(ctx context.Context) error {
-	options := metav1.ListOptions{
-		LabelSelector: p.description.LabelSelector,
-	}
-	logger := log.WithFields(log.Fields{
-		"namespace":     p.description.Namespace,
-		"type":          p.description.Type,
-		"labelselector": p.description.LabelSelector,
-	})
-
-	logger.Debug("fetching initial context")
-
-	pods, err := p.clientset.CoreV1().Pods(p.description.Namespace).List(options)
-	if err != nil {
-		return err
-	}
-	for _, pod := range pods.Items {
-		state := getPodResourceState(&pod)
-		p.podstate[pod.Name] = state
-
-		log.WithFields(log.Fields{
-			"podName":  pod.Name,
-			"podState": state,
-		}).Debug("added to podstate")
-	}
-
-	logger.Debug("fetched context")
-	if match := MatchStateMap(p.podstate, p.description.RequiredStates); match {
-		logger.Debug("match: ", match)
-		return nil
+// runsImage reports whether the pod runs the description's image, in the description's
+// container if it names one. Images can be changed in place in the pod spec, so only the
+// image a container reports in its status counts, and the spec only until it reports one.
+func (p *PodMatcher) runsImage(pod *v1.Pod) bool {
+	if p.description.Image == "" {
+		return true
 	}
-
-	p.watcher, err = p.clientset.CoreV1().Pods(p.description.Namespace).Watch(options)
-	if err != nil {
-		return err
+	images := make(map[string][]string)
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			// the image ID is prefixed with a scheme such as docker-pullable://
+			imageID := status.ImageID
+			if i := strings.Index(imageID, "://"); i >= 0 {
+				imageID = imageID[i+len("://"):]
+			}
+			images[status.Name] = append(images[status.Name], status.Image, imageID)
+		}
 	}
-	log.Info("watching for updates")
-	for event := range p.watcher.ResultChan() {
-		ctxLogger := log.WithFields(log.Fields{
-			"eventType": event.Type,
-		})
-
-		switch event.Type {
-		case watch.Added:
-			pod := event.Object.(*v1.Pod)
-			state := getPodResourceState(pod)
-			p.podstate[pod.Name] = state
-			ctxLogger.WithFields(log.Fields{
-				"podName":  pod.Name,
-				"podState": state,
-			}).Debug("added to pod state")
-		case watch.Modified:
-			pod := event.Object.(*v1.Pod)
-			state := getPodResourceState(pod)
-			p.podstate[pod.Name] = state
-			ctxLogger.WithFields(log.Fields{
-				"podName":  pod.Name,
-				"podState": state,
-			}).Debug("updated pod state")
-		case watch.Deleted:
-			pod := event.Object.(*v1.Pod)
-			_, ok := p.podstate[pod.Name]
-			if ok {
-				delete(p.podstate, pod.Name)
-				ctxLogger.WithFields(log.Fields{
-					"podName": pod.Name,
-				}).Debug("removed from pod state")
+	for _, containers := range [][]v1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if _, ok := images[container.Name]; !ok {
+				images[container.Name] = []string{container.Image}
 			}
-		case watch.Error:
-			// TODO: Do something with this error
-			return nil
 		}
-
-		if MatchStateMap(p.podstate, p.description.RequiredStates) {
-			log.Info("state description matched by cluster")
-			select {
-			case <-p.done:
-			default:
-				close(p.done)
+	}
+	for name, refs := range images {
+		if p.description.Container != "" && name != p.description.Container {
+			continue
+		}
+		for _, ref := range refs {
+			if matched, err := path.Match(p.description.Image, ref); err == nil && matched {
+				return true
 			}
-			break
 		}
 	}
-	return nil
+	return false
 }
 
-func (p *PodMatcher) Done() <-chan bool {
-	return p.done
+// isTerminating reports whether a pod is being deleted. Such pods can still report Ready
+// during a rolling update, seconds before they disappear, so they are not matched.
+func isTerminating(obj interface{}) bool {
+	return obj.(*v1.Pod).DeletionTimestamp != nil
 }
 
-func (p *PodMatcher) Stop(ctx context.Context) error {
-	defer func() {
-		select {
-		case <-p.done:
-		default:
-			close(p.done)
-		}
-	}()
-	if p.watcher != nil {
-		p.watcher.Stop()
+func (p *PodMatcher) evaluate(obj interface{}) (ResourceState, string) {
+	pod := obj.(*v1.Pod)
+	if name := p.description.Container; name != "" {
+		status := findContainerStatus(pod, name)
+		return getContainerResourceState(status, p.description.MaxRestarts), explainContainer(name, status, p.description.MaxRestarts)
 	}
-	return nil
+	return getPodResourceState(pod), explainPod(pod)
 }
 
+// getPodResourceState returns the most advanced state a pod has reached, unless one of its
+// containers is stuck or it cannot be scheduled, in which case that failure is returned.
 func getPodResourceState(pod *v1.Pod) ResourceState {
-	// check for ready
-	for _, condition := range pod.Status.Conditions {
-		if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
-			return ResourceReady
-		}
+	if podCondition(pod, v1.PodReady) == v1.ConditionTrue {
+		return ResourceReady
 	}
 	switch pod.Status.Phase {
 	case v1.PodSucceeded:
 		return ResourceSucceeded
 	case v1.PodFailed:
 		return ResourceFailed
-	default:
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable {
+			return ResourceUnschedulable
+		}
+	}
+	// appending the slices could write into the backing array of the pod in the informer cache
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if status.State.Waiting == nil {
+				continue
+			}
+			switch status.State.Waiting.Reason {
+			case "CrashLoopBackOff":
+				return ResourceCrashLoopBackOff
+			case "ImagePullBackOff":
+				// ErrImagePull is the first, often transient, failure before the kubelet backs off
+				return ResourceImagePullBackOff
+			}
+		}
+	}
+	switch {
+	case podCondition(pod, v1.ContainersReady) == v1.ConditionTrue:
+		return ResourceContainersReady
+	case pod.Status.Phase == v1.PodRunning:
+		return ResourceRunning
+	case podCondition(pod, v1.PodInitialized) == v1.ConditionTrue:
+		return ResourceInitialized
+	case podCondition(pod, v1.PodScheduled) == v1.ConditionTrue:
+		return ResourceScheduled
+	}
+	return resourceWaiting
+}
+
+// findContainerStatus returns the status of the named container or init container, or nil
+// if the pod does not report one yet.
+func findContainerStatus(pod *v1.Pod, name string) *v1.ContainerStatus {
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses} {
+		for i := range statuses {
+			if statuses[i].Name == name {
+				return &statuses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// getContainerResourceState maps the status of a single container onto the pod states:
+// Ready, Running, Succeeded for an exit code of 0 and Failed for any other exit code.
+func getContainerResourceState(status *v1.ContainerStatus, maxRestarts *int32) ResourceState {
+	if status == nil {
+		return resourceWaiting
+	}
+	if maxRestarts != nil && status.RestartCount > *maxRestarts {
+		return ResourceRestartLimitExceeded
+	}
+	switch state := status.State; {
+	case state.Terminated != nil && state.Terminated.ExitCode == 0:
+		// init containers are also ready once they have completed
+		return ResourceSucceeded
+	case state.Terminated != nil:
+		return ResourceFailed
+	case status.Ready:
+		return ResourceReady
+	case state.Running != nil:
+		return ResourceRunning
+	case state.Waiting != nil && state.Waiting.Reason == "CrashLoopBackOff":
+		return ResourceCrashLoopBackOff
+	case state.Waiting != nil && state.Waiting.Reason == "ImagePullBackOff":
+		return ResourceImagePullBackOff
 	}
 	return resourceWaiting
 }
+
+// podCondition returns the status of the pod condition of the given type, or an empty
+// status if the pod does not report it.
+func podCondition(pod *v1.Pod, conditionType v1.PodConditionType) v1.ConditionStatus {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return ""
+}
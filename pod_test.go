@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	watch "k8s.io/apimachinery/pkg/watch"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 	testcore "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func init() {
@@ -51,7 +55,7 @@ func TestPodMatcherSinglePod(t *testing.T) {
 		return true, podlist, nil
 	})
 	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watcher, nil))
-	matcher := NewPodMatcher(fake, description)
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
 	go matcher.Start(context.Background())
 
 	//simulate watch update
@@ -115,7 +119,7 @@ func TestPodMatcherPodAdded(t *testing.T) {
 		return true, podlist, nil
 	})
 	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watcher, nil))
-	matcher := NewPodMatcher(fake, description)
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
 	go matcher.Start(context.Background())
 	// Add a new pod
 	watcher.Add(&v1.Pod{
@@ -227,7 +231,7 @@ func TestPodMatcherPendingPodDeleted(t *testing.T) {
 		return true, podlist, nil
 	})
 	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watcher, nil))
-	matcher := NewPodMatcher(fake, description)
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
 	go matcher.Start(context.Background())
 	// Delete pending pod
 	watcher.Delete(&v1.Pod{
@@ -304,7 +308,7 @@ func TestPodMatcherDeleteRunningPod(t *testing.T) {
 		return true, podlist, nil
 	})
 	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watcher, nil))
-	matcher := NewPodMatcher(fake, description)
+	matcher := NewPodMatcher(NewInformerCache(fake), description)
 	go matcher.Start(context.Background())
 	// Delete pending pod
 	watcher.Delete(&v1.Pod{
@@ -453,4 +457,188 @@ func TestPodValidator(t *testing.T) {
 	if err := validator.Validate(context.Background(), emptyRequiredStatesDescription); err == nil || err.Error() != ErrNoRequiredStates(emptyRequiredStatesDescription).Error() {
 		t.Fatalf("validation should fail with: %v , instead it failed with %v", ErrNoRequiredStates(badDescription), err)
 	}
+
+	badFieldSelectorDescription := StateDescription{
+		Type:           "Pod",
+		Namespace:      "test-ns",
+		FieldSelector:  "status.phase",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+
+	if err := validator.Validate(context.Background(), badFieldSelectorDescription); err == nil {
+		t.Fatalf("validation should fail for field selector %q", badFieldSelectorDescription.FieldSelector)
+	}
+}
+
+func TestPodMatcherIgnoresTerminatingPods(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	now := metav1.Now()
+	terminating := newTestPod("old", "1", true)
+	terminating.DeletionTimestamp = &now
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	store.Add(terminating)
+	store.Add(newTestPod("new", "2", false))
+
+	matcher := NewPodMatcher(nil, description).(*PodMatcher)
+	matcher.selector = labels.Everything()
+	matcher.store = store
+	matcher.replace()
+
+	expected := map[string]ResourceState{"test-ns/new": ResourceRunning}
+	if states := matcher.States(); !reflect.DeepEqual(states, expected) {
+		t.Fatalf("terminating pods should not be part of the state, got %v", states)
+	}
+
+	// a ready pod that starts terminating leaves the state
+	store.Update(newTestPod("new", "3", true))
+	matcher.OnUpdate(nil, newTestPod("new", "3", true))
+	deleted := newTestPod("new", "4", true)
+	deleted.DeletionTimestamp = &now
+	store.Update(deleted)
+	matcher.OnUpdate(nil, deleted)
+	if states := matcher.States(); len(states) != 0 {
+		t.Fatalf("terminating pod should have been removed, got %v", states)
+	}
+}
+
+func TestGetPodResourceState(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   v1.PodStatus
+		expected ResourceState
+	}{
+		{"pending", v1.PodStatus{Phase: v1.PodPending}, resourceWaiting},
+		{"scheduled", v1.PodStatus{
+			Phase:      v1.PodPending,
+			Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}},
+		}, ResourceScheduled},
+		{"initialized", v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				{Type: v1.PodInitialized, Status: v1.ConditionTrue},
+			},
+		}, ResourceInitialized},
+		{"running", v1.PodStatus{Phase: v1.PodRunning}, ResourceRunning},
+		{"containers ready", v1.PodStatus{
+			Phase: v1.PodRunning,
+			Conditions: []v1.PodCondition{
+				{Type: v1.ContainersReady, Status: v1.ConditionTrue},
+				{Type: v1.PodReady, Status: v1.ConditionFalse},
+			},
+		}, ResourceContainersReady},
+		{"unschedulable", v1.PodStatus{
+			Phase:      v1.PodPending,
+			Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable}},
+		}, ResourceUnschedulable},
+		{"crash loop", v1.PodStatus{
+			Phase: v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+			},
+		}, ResourceCrashLoopBackOff},
+		{"image pull", v1.PodStatus{
+			Phase: v1.PodPending,
+			InitContainerStatuses: []v1.ContainerStatus{
+				{Name: "init", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+			},
+		}, ResourceImagePullBackOff},
+		{"first image pull error", v1.PodStatus{
+			Phase: v1.PodPending,
+			InitContainerStatuses: []v1.ContainerStatus{
+				{Name: "init", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+			},
+		}, resourceWaiting},
+	}
+	for _, test := range tests {
+		if state := getPodResourceState(&v1.Pod{Status: test.status}); state != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, state)
+		}
+	}
+}
+
+func TestGetContainerResourceState(t *testing.T) {
+	maxRestarts := int32(2)
+	pod := &v1.Pod{
+		Status: v1.PodStatus{
+			InitContainerStatuses: []v1.ContainerStatus{
+				{Name: "schema", Ready: true, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "postgres", Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				{Name: "istio-proxy", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				{Name: "loader", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}},
+				{Name: "flaky", RestartCount: 3, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			},
+		},
+	}
+	tests := []struct {
+		container string
+		expected  ResourceState
+	}{
+		{"schema", ResourceSucceeded},
+		{"postgres", ResourceReady},
+		{"istio-proxy", ResourceRunning},
+		{"loader", ResourceFailed},
+		{"flaky", ResourceRestartLimitExceeded},
+		{"missing", resourceWaiting},
+	}
+	for _, test := range tests {
+		if state := getContainerResourceState(findContainerStatus(pod, test.container), &maxRestarts); state != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.container, test.expected, state)
+		}
+	}
+	if reason := explainContainer("flaky", findContainerStatus(pod, "flaky"), &maxRestarts); reason != "container flaky restarted 3 times (maxRestarts 2)" {
+		t.Errorf("unexpected explanation for flaky container: %q", reason)
+	}
+}
+
+func TestPodMatcherImage(t *testing.T) {
+	pod := func(name, image, imageID string) *v1.Pod {
+		p := newTestPod(name, "1", true)
+		p.Spec.Containers = []v1.Container{
+			{Name: "api", Image: image},
+			{Name: "istio-proxy", Image: "istio/proxyv2:1.0.0"},
+		}
+		p.Status.ContainerStatuses = []v1.ContainerStatus{
+			{Name: "api", Image: image, ImageID: "docker-pullable://" + imageID},
+		}
+		return p
+	}
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	store.Add(pod("old", "registry/api:1.3.0", "registry/api@sha256:aaa"))
+	store.Add(pod("new", "registry/api:1.4.0", "registry/api@sha256:bbb"))
+	// the spec was updated in place, but the container still runs the old image
+	updated := pod("updated", "registry/api:1.3.0", "registry/api@sha256:aaa")
+	updated.Spec.Containers[0].Image = "registry/api:1.4.0"
+	store.Add(updated)
+
+	tests := []struct {
+		description StateDescription
+		expected    []string
+	}{
+		{StateDescription{Image: "registry/api:1.4.*"}, []string{"test-ns/new"}},
+		{StateDescription{Image: "registry/api@sha256:aaa"}, []string{"test-ns/old", "test-ns/updated"}},
+		{StateDescription{Image: "istio/proxyv2:*"}, []string{"test-ns/new", "test-ns/old", "test-ns/updated"}},
+		{StateDescription{Image: "istio/proxyv2:*", Container: "api"}, []string{}},
+	}
+	for _, test := range tests {
+		test.description.Type = PodResource
+		matcher := NewPodMatcher(nil, test.description).(*PodMatcher)
+		matcher.selector = labels.Everything()
+		matcher.store = store
+		matcher.replace()
+		keys := []string{}
+		for key := range matcher.States() {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.description, test.expected, keys)
+		}
+	}
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ProgressIntervalEnv sets how often a summary of every description is logged, e.g. "30s".
+	// A zero interval disables the summaries.
+	ProgressIntervalEnv = "KUBEWAIT_PROGRESS_INTERVAL"
+	// DefaultProgressInterval is used when ProgressIntervalEnv is not set.
+	DefaultProgressInterval = 30 * time.Second
+	// maxLaggards limits the number of resource names included in a single summary.
+	maxLaggards = 10
+)
+
+// reportProgress logs a summary of every description each interval until ctx or done is closed.
+func reportProgress(ctx context.Context, interval time.Duration, descriptions []StateDescription, matchers []Matcher, done <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		for i, description := range descriptions {
+			logProgress(DescriptionResult{
+				StateDescription: description,
+				States:           matchers[i].States(),
+				Reasons:          matchers[i].Reasons(),
+			})
+		}
+	}
+}
+
+func logProgress(result DescriptionResult) {
+	logger := result.StateDescription.Logger()
+	if result.StateDescription.MatchStates(result.States) {
+		logger.WithFields(progressFields(result)).Info("description matched")
+		return
+	}
+	logger.WithFields(progressFields(result)).Info("waiting for description")
+}
+
+// logUnmatched logs a warning with the laggards of every description that did not match.
+func logUnmatched(results []DescriptionResult) {
+	for _, result := range results {
+		if result.Matched {
+			continue
+		}
+		logger := result.StateDescription.Logger().WithFields(progressFields(result))
+		if result.Err != nil {
+			logger = logger.WithError(result.Err)
+		}
+		logger.Warn("description not matched")
+	}
+}
+
+// progressFields counts the resources of a result and names up to maxLaggards of those not
+// yet in a required state.
+func progressFields(result DescriptionResult) log.Fields {
+	laggards := result.Offending()
+	fields := log.Fields{
+		"resources":       len(result.States),
+		"inRequiredState": len(result.States) - len(laggards),
+	}
+	if len(laggards) == 0 {
+		return fields
+	}
+	if len(laggards) > maxLaggards {
+		fields["laggardsOmitted"] = len(laggards) - maxLaggards
+		laggards = laggards[:maxLaggards]
+	}
+	pairs := make([]string, 0, len(laggards))
+	for _, name := range laggards {
+		pairs = append(pairs, result.Describe(name))
+	}
+	fields["laggards"] = pairs
+	return fields
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogProgress(t *testing.T) {
+	hook := test.NewLocal(log.StandardLogger())
+	defer hook.Reset()
+
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		LabelSelector:  "app=test",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	logProgress(DescriptionResult{
+		StateDescription: description,
+		States: map[string]ResourceState{
+			"pod-1": ResourceReady,
+			"pod-2": resourceWaiting,
+			"pod-3": ResourceFailed,
+		},
+		Reasons: map[string]string{
+			"pod-2": "container app: CrashLoopBackOff",
+		},
+	})
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != log.InfoLevel {
+		t.Fatalf("expected an info level summary, got %v", entry)
+	}
+	if entry.Data["resources"] != 3 || entry.Data["inRequiredState"] != 1 {
+		t.Fatalf("unexpected counts in summary: %v", entry.Data)
+	}
+	expected := []string{"pod-2=waiting (container app: CrashLoopBackOff)", "pod-3=Failed"}
+	if !reflect.DeepEqual(entry.Data["laggards"], expected) {
+		t.Fatalf("expected laggards %v, got %v", expected, entry.Data["laggards"])
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// SecretMatcher matches secrets that exist and hold the description's keys.
+type SecretMatcher struct {
+	*informerMatcher
+}
+
+func NewSecretMatcher(informers *InformerCache, description StateDescription) Matcher {
+	m := &SecretMatcher{}
+	m.informerMatcher = newInformerMatcher(informers, description, m.evaluate)
+	return m
+}
+
+func (m *SecretMatcher) evaluate(obj interface{}) (ResourceState, string) {
+	secret := obj.(*v1.Secret)
+	sizes := make(map[string]int, len(secret.Data))
+	for key, value := range secret.Data {
+		sizes[key] = len(value)
+	}
+	return evaluateKeys(m.description, sizes)
+}
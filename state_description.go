@@ -1,18 +1,260 @@
 package main
 
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	funk "github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
 // ResourceState describes the states a resource can be in.
 type ResourceState string
 
 // ResourceType describes the types of resources that can be watched.
 type ResourceType string
 
+// Namespaced reports whether resources of the type live in a namespace.
+func (t ResourceType) Namespaced() bool {
+	return t != NodeResource && t != NamespaceResource
+}
+
 // StateDescription is a JSON description of a resource and the state that the resource must be in
 // so that the cluster state match succeeds. If no such resources are found, the match does not succeed.
 type StateDescription struct {
 	Type           ResourceType    `json:"type"`
-	LabelSelector  string          `json:"labelSelector",omitempty`
+	LabelSelector  string          `json:"labelSelector,omitempty"`
 	RequiredStates []ResourceState `json:"requiredStates"`
-	Namespace      string          `json:"namespace",omitempty`
+	// FailOn lists states that fail the wait as soon as any resource reaches one of them.
+	FailOn []ResourceState `json:"failOn,omitempty"`
+	// Namespace is the namespace of the resources, or "*" for all namespaces.
+	Namespace string `json:"namespace,omitempty"`
+	// NamespaceSelector restricts the description to resources in namespaces with matching labels.
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+	// FieldSelector is applied by the API server to both the list and the watch, e.g.
+	// "status.phase!=Succeeded" or "metadata.name=postgres-0".
+	FieldSelector string `json:"fieldSelector,omitempty"`
+	// Name, NamePrefix and NameGlob restrict the description to resources with a matching name,
+	// for resources that do not carry a unique label. NameGlob uses path.Match syntax, e.g. "migrate-*".
+	Name       string `json:"name,omitempty"`
+	NamePrefix string `json:"namePrefix,omitempty"`
+	NameGlob   string `json:"nameGlob,omitempty"`
+	// Container bases the state of a pod on one of its containers or init containers instead
+	// of the pod as a whole. MaxRestarts puts the container in the RestartLimitExceeded state
+	// once it has restarted more often.
+	Container   string `json:"container,omitempty"`
+	MaxRestarts *int32 `json:"maxRestarts,omitempty"`
+	// MinSucceeded puts a job in the Succeeded state once at least that many of its pods succeeded.
+	MinSucceeded *int32 `json:"minSucceeded,omitempty"`
+	// Run chooses the job of a cron job whose state is matched: CronJobRunLatest, the default,
+	// or CronJobRunNext.
+	Run string `json:"run,omitempty"`
+	// CreatedAfter ignores resources created before a time: an RFC 3339 timestamp, a duration
+	// before kubewait started such as "2h", or CreatedAfterPodStart.
+	CreatedAfter string `json:"createdAfter,omitempty"`
+	// OwnerKind and OwnerName select resources by a controller anywhere up their chain of
+	// owners, e.g. the deployment of a pod's replica set.
+	OwnerKind string `json:"ownerKind,omitempty"`
+	OwnerName string `json:"ownerName,omitempty"`
+	// HelmRelease and HelmRevision select resources installed by a Helm release, through
+	// the resource itself or one of its owners.
+	HelmRelease  string `json:"helmRelease,omitempty"`
+	HelmRevision string `json:"helmRevision,omitempty"`
+	// Image restricts pods to those whose container, or any container if Container is not set,
+	// runs an image matching the pattern. It uses path.Match syntax and is matched against both
+	// the image of the container spec and the digest it resolved to, e.g. "registry/api:1.4.*"
+	// or "registry/api@sha256:6b7e...".
+	Image string `json:"image,omitempty"`
+	// MinVersion restricts the description to resources whose VersionLabel label or annotation
+	// is a version of at least MinVersion.
+	MinVersion string `json:"minVersion,omitempty"`
+	// MinCount makes the description match once at least that many resources are in a required
+	// state, instead of all of them.
+	MinCount *int32 `json:"minCount,omitempty"`
+	// Keys are the keys a config map or secret must hold to exist, with a non-empty value
+	// if NonEmptyKeys is set.
+	Keys         []string `json:"keys,omitempty"`
+	NonEmptyKeys bool     `json:"nonEmptyKeys,omitempty"`
+
+	// index is the position of the description in the list it was loaded from.
+	index int
+	// createdAfter is CreatedAfter resolved by ResolveCreatedAfter.
+	createdAfter time.Time
+}
+
+// VersionLabel is the label, or annotation, compared with MinVersion.
+const VersionLabel = "app.kubernetes.io/version"
+
+// CreatedAfterPodStart is the CreatedAfter value for resources created after kubewait's own pod started.
+const CreatedAfterPodStart = "podStart"
+
+// Logger returns a log entry carrying the fields that identify the description.
+func (d StateDescription) Logger() *log.Entry {
+	fields := log.Fields{
+		"description": d.index,
+		"type":        d.Type,
+		"namespace":   d.Namespace,
+		"selector":    d.LabelSelector,
+	}
+	optional := map[string]string{
+		"fieldSelector":     d.FieldSelector,
+		"namespaceSelector": d.NamespaceSelector,
+		"name":              d.Name,
+		"namePrefix":        d.NamePrefix,
+		"nameGlob":          d.NameGlob,
+		"container":         d.Container,
+		"createdAfter":      d.CreatedAfter,
+		"ownerKind":         d.OwnerKind,
+		"ownerName":         d.OwnerName,
+		"helmRelease":       d.HelmRelease,
+		"helmRevision":      d.HelmRevision,
+		"image":             d.Image,
+		"minVersion":        d.MinVersion,
+	}
+	for field, value := range optional {
+		if value != "" {
+			fields[field] = value
+		}
+	}
+	return log.WithFields(fields)
+}
+
+func (d StateDescription) String() string {
+	selector := fmt.Sprintf("%q", d.LabelSelector)
+	if d.FieldSelector != "" {
+		selector += fmt.Sprintf(" with fields %q", d.FieldSelector)
+	}
+	switch {
+	case d.Name != "":
+		selector += fmt.Sprintf(" named %q", d.Name)
+	case d.NamePrefix != "":
+		selector += fmt.Sprintf(" named %q", d.NamePrefix+"*")
+	}
+	if d.NameGlob != "" {
+		selector += fmt.Sprintf(" matching %q", d.NameGlob)
+	}
+	if d.Container != "" {
+		selector += fmt.Sprintf(" container %q", d.Container)
+	}
+	if d.CreatedAfter != "" {
+		selector += fmt.Sprintf(" created after %q", d.CreatedAfter)
+	}
+	if d.OwnerKind != "" || d.OwnerName != "" {
+		selector += fmt.Sprintf(" owned by %s %q", d.OwnerKind, d.OwnerName)
+	}
+	if d.HelmRelease != "" || d.HelmRevision != "" {
+		selector += fmt.Sprintf(" of release %q revision %q", d.HelmRelease, d.HelmRevision)
+	}
+	if d.Image != "" {
+		selector += fmt.Sprintf(" running %q", d.Image)
+	}
+	if d.MinVersion != "" {
+		selector += fmt.Sprintf(" at version %q or later", d.MinVersion)
+	}
+	namespace := fmt.Sprintf("namespace %q", d.Namespace)
+	if d.NamespaceSelector != "" {
+		namespace = fmt.Sprintf("namespaces %q", d.NamespaceSelector)
+	}
+	if !d.Type.Namespaced() {
+		return fmt.Sprintf("%s %s %v", d.Type, selector, d.RequiredStates)
+	}
+	return fmt.Sprintf("%s %s in %s %v", d.Type, selector, namespace, d.RequiredStates)
+}
+
+// InformerNamespace returns the namespace to list and watch, which spans all namespaces
+// when the description selects namespaces by label or the resources are not namespaced.
+func (d StateDescription) InformerNamespace() string {
+	if d.Namespace == AllNamespaces || d.NamespaceSelector != "" || !d.Type.Namespaced() {
+		return metav1.NamespaceAll
+	}
+	return d.Namespace
+}
+
+// MatchesName reports whether name satisfies the name, namePrefix and nameGlob fields.
+func (d StateDescription) MatchesName(name string) bool {
+	if d.Name != "" && name != d.Name {
+		return false
+	}
+	if !strings.HasPrefix(name, d.NamePrefix) {
+		return false
+	}
+	if d.NameGlob != "" {
+		matched, err := path.Match(d.NameGlob, name)
+		return err == nil && matched
+	}
+	return true
+}
+
+// MatchStates reports whether the states of the resources satisfy the description: all of
+// them must be in a required state or, with MinCount, at least MinCount of them.
+func (d StateDescription) MatchStates(states map[string]ResourceState) bool {
+	if d.MinCount == nil {
+		return MatchStateMap(states, d.expandStates(d.RequiredStates))
+	}
+	return int32(countStates(states, d.expandStates(d.RequiredStates))) >= *d.MinCount
+}
+
+// expandStates returns the states with Ready replaced by the states of a ready node for node
+// descriptions, as nodes are never reported Ready themselves.
+func (d StateDescription) expandStates(states []ResourceState) []ResourceState {
+	if d.Type != NodeResource || !funk.Contains(states, ResourceReady) {
+		return states
+	}
+	expanded := make([]ResourceState, 0, len(states)+len(nodeReadyStates))
+	for _, state := range states {
+		if state != ResourceReady {
+			expanded = append(expanded, state)
+		}
+	}
+	return append(expanded, nodeReadyStates...)
+}
+
+// countStates returns how many resources are in one of the required states.
+func countStates(states map[string]ResourceState, required []ResourceState) int {
+	count := 0
+	for _, state := range states {
+		if funk.Contains(required, state) {
+			count++
+		}
+	}
+	return count
+}
+
+// MatchesVersion reports whether a resource with the given labels and annotations is at
+// MinVersion or later. Resources without a parseable version do not match.
+func (d StateDescription) MatchesVersion(labels, annotations map[string]string) bool {
+	if d.MinVersion == "" {
+		return true
+	}
+	minVersion, err := version.ParseGeneric(d.MinVersion)
+	if err != nil {
+		return false
+	}
+	value, ok := labels[VersionLabel]
+	if !ok {
+		value = annotations[VersionLabel]
+	}
+	current, err := version.ParseGeneric(value)
+	return err == nil && current.AtLeast(minVersion)
+}
+
+// ServerFieldSelector returns the field selector sent to the API server, which narrows the
+// field selector down to the resource named by Name, if any.
+func (d StateDescription) ServerFieldSelector() string {
+	if d.Name == "" {
+		return d.FieldSelector
+	}
+	byName := fields.OneTermEqualSelector("metadata.name", d.Name)
+	selector, err := fields.ParseSelector(d.FieldSelector)
+	if err != nil || selector.Empty() {
+		return byName.String()
+	}
+	return fields.AndSelectors(selector, byName).String()
 }
 
 const (
@@ -20,8 +262,24 @@ const (
 	PodResource ResourceType = "Pod"
 	// JobResource is used to match k8s jobs.
 	JobResource ResourceType = "Job"
+	// CronJobResource is used to match k8s cron jobs by the state of their jobs.
+	CronJobResource ResourceType = "CronJob"
+	// NodeResource is used to match k8s nodes.
+	NodeResource ResourceType = "Node"
+	// NamespaceResource is used to match k8s namespaces, and to follow their labels for
+	// namespace selectors.
+	NamespaceResource ResourceType = "Namespace"
+	// ConfigMapResource and SecretResource are used to wait for k8s config maps and secrets.
+	ConfigMapResource ResourceType = "ConfigMap"
+	SecretResource    ResourceType = "Secret"
+	// ReplicaSetResource and DeploymentResource are used to follow the owners of pods.
+	ReplicaSetResource ResourceType = "ReplicaSet"
+	DeploymentResource ResourceType = "Deployment"
 )
 
+// AllNamespaces is the namespace of descriptions that span every namespace.
+const AllNamespaces = "*"
+
 const (
 	ResourceReady     ResourceState = "Ready"
 	ResourceSucceeded ResourceState = "Succeeded"
@@ -29,4 +287,37 @@ const (
 	resourceWaiting   ResourceState = "waiting"
 	ResourceComplete  ResourceState = "Complete"
 	ResourceRunning   ResourceState = "Running"
+
+	// Pod states before Ready, from the most to the least advanced.
+	ResourceContainersReady ResourceState = "ContainersReady"
+	ResourceInitialized     ResourceState = "Initialized"
+	ResourceScheduled       ResourceState = "Scheduled"
+
+	// Pod states that usually mean the pod will not become ready, for use in FailOn.
+	ResourceCrashLoopBackOff ResourceState = "CrashLoopBackOff"
+	ResourceImagePullBackOff ResourceState = "ImagePullBackOff"
+	ResourceUnschedulable    ResourceState = "Unschedulable"
+	// ResourceRestartLimitExceeded is the state of a container that restarted more than MaxRestarts times.
+	ResourceRestartLimitExceeded ResourceState = "RestartLimitExceeded"
+
+	// Job states besides Complete, Failed and Running.
+	ResourceSuspended            ResourceState = "Suspended"
+	ResourceBackoffLimitExceeded ResourceState = "BackoffLimitExceeded"
+
+	// Node states. A node is Schedulable when it is ready and not cordoned, Cordoned when it is
+	// ready but cordoned, and in a pressure state when it is ready but the kubelet reports the
+	// pressure condition.
+	ResourceSchedulable        ResourceState = "Schedulable"
+	ResourceCordoned           ResourceState = "Cordoned"
+	ResourceMemoryPressure     ResourceState = "MemoryPressure"
+	ResourceDiskPressure       ResourceState = "DiskPressure"
+	ResourcePIDPressure        ResourceState = "PIDPressure"
+	ResourceNetworkUnavailable ResourceState = "NetworkUnavailable"
+
+	// Namespace states.
+	ResourceActive      ResourceState = "Active"
+	ResourceTerminating ResourceState = "Terminating"
+
+	// ResourceExists is the state of config maps and secrets that hold the required keys.
+	ResourceExists ResourceState = "Exists"
 )
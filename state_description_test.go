@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestMatchesName(t *testing.T) {
+	tests := []struct {
+		description StateDescription
+		name        string
+		expected    bool
+	}{
+		{StateDescription{}, "anything", true},
+		{StateDescription{Name: "migrate-v2"}, "migrate-v2", true},
+		{StateDescription{Name: "migrate-v2"}, "migrate-v21", false},
+		{StateDescription{NamePrefix: "migrate-"}, "migrate-v2", true},
+		{StateDescription{NamePrefix: "migrate-"}, "seed-v2", false},
+		{StateDescription{NameGlob: "migrate-*-job"}, "migrate-v2-job", true},
+		{StateDescription{NameGlob: "migrate-*-job"}, "migrate-v2", false},
+		{StateDescription{NamePrefix: "migrate-", NameGlob: "*-v2"}, "seed-v2", false},
+	}
+	for _, test := range tests {
+		if matched := test.description.MatchesName(test.name); matched != test.expected {
+			t.Errorf("%v: expected MatchesName(%q) to be %v", test.description, test.name, test.expected)
+		}
+	}
+}
+
+func TestServerFieldSelector(t *testing.T) {
+	tests := []struct {
+		description StateDescription
+		expected    string
+	}{
+		{StateDescription{}, ""},
+		{StateDescription{FieldSelector: "status.phase!=Succeeded"}, "status.phase!=Succeeded"},
+		{StateDescription{Name: "postgres-0"}, "metadata.name=postgres-0"},
+		{StateDescription{Name: "postgres-0", FieldSelector: "status.phase!=Succeeded"}, "status.phase!=Succeeded,metadata.name=postgres-0"},
+	}
+	for _, test := range tests {
+		if selector := test.description.ServerFieldSelector(); selector != test.expected {
+			t.Errorf("%v: expected field selector %q, got %q", test.description, test.expected, selector)
+		}
+	}
+}
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		labels, annotations map[string]string
+		expected            bool
+	}{
+		{map[string]string{VersionLabel: "1.4.0"}, nil, true},
+		{map[string]string{VersionLabel: "v1.10.2"}, nil, true},
+		{map[string]string{VersionLabel: "1.3.9"}, nil, false},
+		{nil, map[string]string{VersionLabel: "2.0"}, true},
+		{map[string]string{VersionLabel: "latest"}, nil, false},
+		{nil, nil, false},
+	}
+	description := StateDescription{MinVersion: "1.4"}
+	for _, test := range tests {
+		if matched := description.MatchesVersion(test.labels, test.annotations); matched != test.expected {
+			t.Errorf("labels %v, annotations %v: expected %v", test.labels, test.annotations, test.expected)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	funk "github.com/thoas/go-funk"
+)
+
+const (
+	// TerminationLogEnv overrides the path the termination message is written to.
+	TerminationLogEnv = "KUBEWAIT_TERMINATION_LOG"
+	// DefaultTerminationLog is the kubelet's default terminationMessagePath.
+	DefaultTerminationLog = "/dev/termination-log"
+	// maxTerminationMessageLength is the size limit the kubelet enforces on termination messages.
+	maxTerminationMessageLength = 4096
+)
+
+// DescriptionResult records the outcome of waiting on a single StateDescription.
+type DescriptionResult struct {
+	StateDescription
+	Matched bool
+	States  map[string]ResourceState
+	// Reasons explains, by resource name, why a resource is not in a required state.
+	Reasons map[string]string
+	Err     error
+	// Stopped is why the wait on the description was stopped, if it was: context.DeadlineExceeded
+	// for a timeout, context.Canceled for a signal, or the failure of another description.
+	Stopped error
+}
+
+// Offending returns the sorted names of resources that are not in one of the required states.
+func (r DescriptionResult) Offending() []string {
+	names := make([]string, 0)
+	required := r.expandStates(r.RequiredStates)
+	for name, state := range r.States {
+		if !funk.Contains(required, state) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe formats the state of the named resource, with the reason it is in that state if known.
+func (r DescriptionResult) Describe(name string) string {
+	if reason := r.Reasons[name]; reason != "" {
+		return fmt.Sprintf("%s=%s (%s)", name, r.States[name], reason)
+	}
+	return fmt.Sprintf("%s=%s", name, r.States[name])
+}
+
+// TerminationMessage builds a short summary of the results, one line per description.
+func TerminationMessage(results []DescriptionResult, err error) string {
+	var b strings.Builder
+	if err != nil {
+		fmt.Fprintf(&b, "error: %v\n", err)
+	}
+	for _, result := range results {
+		if result.Matched {
+			fmt.Fprintf(&b, "matched: %v\n", result.StateDescription)
+			continue
+		}
+		fmt.Fprintf(&b, "not matched: %v", result.StateDescription)
+		switch offending := result.Offending(); {
+		case result.Err != nil:
+			fmt.Fprintf(&b, ": %v", result.Err)
+		case len(result.States) == 0:
+			b.WriteString(": no resources found")
+		case len(offending) == 0 && result.MinCount != nil:
+			// with minCount every resource can be in a required state without enough of them
+			fmt.Fprintf(&b, ": %d of %d required", len(result.States), *result.MinCount)
+		case len(offending) == 0 && result.Stopped == context.DeadlineExceeded:
+			// every resource was in a required state, but the deadline hit before the match was recorded
+			b.WriteString(": timed out")
+		case len(offending) == 0 && result.Stopped == context.Canceled:
+			b.WriteString(": interrupted")
+		case len(offending) == 0 && result.Stopped != nil:
+			// stopped because another description failed
+			fmt.Fprintf(&b, ": %v", result.Stopped)
+		default:
+			pairs := make([]string, 0, len(offending))
+			for _, name := range offending {
+				pairs = append(pairs, result.Describe(name))
+			}
+			fmt.Fprintf(&b, ": %s", strings.Join(pairs, ", "))
+		}
+		b.WriteString("\n")
+	}
+	message := b.String()
+	if len(message) > maxTerminationMessageLength {
+		message = message[:maxTerminationMessageLength-4] + "...\n"
+	}
+	return message
+}
+
+// WriteTerminationMessage writes the summary of results to path so that it shows up in
+// `kubectl describe pod`.
+func WriteTerminationMessage(path string, results []DescriptionResult, err error) error {
+	return ioutil.WriteFile(path, []byte(TerminationMessage(results, err)), 0644)
+}
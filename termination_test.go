@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTerminationMessage(t *testing.T) {
+	results := []DescriptionResult{
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				Namespace:      "test-ns",
+				LabelSelector:  "app=postgres",
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			Matched: true,
+			States:  map[string]ResourceState{"postgres-0": ResourceReady},
+		},
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           JobResource,
+				Namespace:      "test-ns",
+				LabelSelector:  "app=seeder",
+				RequiredStates: []ResourceState{ResourceComplete},
+			},
+			States: map[string]ResourceState{
+				"seeder-b": ResourceFailed,
+				"seeder-a": ResourceRunning,
+				"seeder-c": ResourceComplete,
+			},
+			Reasons: map[string]string{
+				"seeder-b": "job failed: BackoffLimitExceeded",
+			},
+		},
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				Namespace:      "test-ns",
+				LabelSelector:  "app=redis",
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			States: map[string]ResourceState{},
+		},
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				Namespace:      "other-ns",
+				LabelSelector:  "app=redis",
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			Err: errors.New("forbidden"),
+		},
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				Namespace:      "test-ns",
+				LabelSelector:  "app=api",
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			States:  map[string]ResourceState{"api-0": ResourceReady},
+			Stopped: context.DeadlineExceeded,
+		},
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				Namespace:      "test-ns",
+				LabelSelector:  "app=worker",
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			States:  map[string]ResourceState{"worker-0": ResourceReady},
+			Stopped: context.Canceled,
+		},
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				Namespace:      "test-ns",
+				LabelSelector:  "app=cache",
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			States:  map[string]ResourceState{"cache-0": ResourceReady},
+			Stopped: errors.New(`stopped after Pod "app=api" in namespace "test-ns" [Ready] failed`),
+		},
+	}
+
+	message := TerminationMessage(results, nil)
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	expected := []string{
+		`matched: Pod "app=postgres" in namespace "test-ns" [Ready]`,
+		`not matched: Job "app=seeder" in namespace "test-ns" [Complete]: seeder-a=Running, seeder-b=Failed (job failed: BackoffLimitExceeded)`,
+		`not matched: Pod "app=redis" in namespace "test-ns" [Ready]: no resources found`,
+		`not matched: Pod "app=redis" in namespace "other-ns" [Ready]: forbidden`,
+		`not matched: Pod "app=api" in namespace "test-ns" [Ready]: timed out`,
+		`not matched: Pod "app=worker" in namespace "test-ns" [Ready]: interrupted`,
+		`not matched: Pod "app=cache" in namespace "test-ns" [Ready]: stopped after Pod "app=api" in namespace "test-ns" [Ready] failed`,
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %q", len(expected), len(lines), message)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestTerminationMessageTruncated(t *testing.T) {
+	results := []DescriptionResult{
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           PodResource,
+				RequiredStates: []ResourceState{ResourceReady},
+			},
+			States: make(map[string]ResourceState),
+		},
+	}
+	for i := 0; i < 1000; i++ {
+		results[0].States[strings.Repeat("x", i)] = resourceWaiting
+	}
+	if message := TerminationMessage(results, nil); len(message) > maxTerminationMessageLength {
+		t.Fatalf("termination message is %d bytes long", len(message))
+	}
+}
+
+func TestTerminationMessageMinCount(t *testing.T) {
+	minCount := int32(3)
+	results := []DescriptionResult{
+		DescriptionResult{
+			StateDescription: StateDescription{
+				Type:           NodeResource,
+				LabelSelector:  "pool=gpu",
+				RequiredStates: []ResourceState{ResourceSchedulable},
+				MinCount:       &minCount,
+			},
+			States: map[string]ResourceState{"gpu-1": ResourceSchedulable, "gpu-2": ResourceSchedulable},
+		},
+	}
+	expected := `not matched: Node "pool=gpu" [Schedulable]: 2 of 3 required` + "\n"
+	if message := TerminationMessage(results, nil); message != expected {
+		t.Fatalf("expected %q, got %q", expected, message)
+	}
+}
@@ -3,10 +3,20 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
+// serviceAccountNamespaceFile holds the namespace of the pod's service account. It is a
+// variable so that tests can point it elsewhere.
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 func GetStateDescriptionsFromEnv(env string) ([]StateDescription, error) {
 	strval, ok := os.LookupEnv(env)
 	if !ok {
@@ -18,9 +28,115 @@ func GetStateDescriptionsFromEnv(env string) ([]StateDescription, error) {
 	if err != nil {
 		return []StateDescription{}, err
 	}
+	for i := range descriptions {
+		descriptions[i].index = i
+	}
 	return descriptions, nil
 }
 
+// SetDefaultNamespace sets the namespace of descriptions that neither name a namespace nor
+// select namespaces by label to the namespace kubewait runs in. An empty namespace would
+// otherwise span all namespaces.
+func SetDefaultNamespace(descriptions []StateDescription) error {
+	namespace := ""
+	for i := range descriptions {
+		if descriptions[i].Namespace != "" || descriptions[i].NamespaceSelector != "" || !descriptions[i].Type.Namespaced() {
+			continue
+		}
+		if namespace == "" {
+			var err error
+			if namespace, err = podNamespace(); err != nil {
+				return err
+			}
+		}
+		descriptions[i].Namespace = namespace
+	}
+	return nil
+}
+
+// ResolveCreatedAfter resolves the CreatedAfter field of every description relative to now,
+// looking up when kubewait's own pod started if any description needs it.
+func ResolveCreatedAfter(clientset kubernetes.Interface, descriptions []StateDescription, now time.Time) error {
+	var podStart time.Time
+	for i := range descriptions {
+		value := descriptions[i].CreatedAfter
+		if value != CreatedAfterPodStart {
+			threshold, err := parseCreatedAfter(value, now)
+			if err != nil {
+				return err
+			}
+			descriptions[i].createdAfter = threshold
+			continue
+		}
+		if podStart.IsZero() {
+			var err error
+			if podStart, err = podStartTime(clientset); err != nil {
+				return err
+			}
+		}
+		descriptions[i].createdAfter = podStart
+	}
+	return nil
+}
+
+// parseCreatedAfter parses a CreatedAfter value other than CreatedAfterPodStart. An empty
+// value returns the zero time, which does not filter anything.
+func parseCreatedAfter(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if threshold, err := time.Parse(time.RFC3339, value); err == nil {
+		return threshold, nil
+	}
+	if age, err := time.ParseDuration(value); err == nil {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("createdAfter %q is neither a timestamp, a duration nor %q", value, CreatedAfterPodStart)
+}
+
+// podStartTime returns when the pod kubewait runs in started.
+func podStartTime(clientset kubernetes.Interface) (time.Time, error) {
+	name := os.Getenv(PodNameEnv)
+	if name == "" {
+		return time.Time{}, fmt.Errorf("createdAfter %q requires %s", CreatedAfterPodStart, PodNameEnv)
+	}
+	namespace, err := podNamespace()
+	if err != nil {
+		return time.Time{}, err
+	}
+	pod, err := clientset.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time, nil
+	}
+	return pod.CreationTimestamp.Time, nil
+}
+
+// podNamespace returns the namespace kubewait runs in, from PodNamespaceEnv or the service account.
+func podNamespace() (string, error) {
+	if namespace := os.Getenv(PodNamespaceEnv); namespace != "" {
+		return namespace, nil
+	}
+	data, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", fmt.Errorf("no namespace given and %s not set: %v", PodNamespaceEnv, err)
+	}
+	if namespace := strings.TrimSpace(string(data)); namespace != "" {
+		return namespace, nil
+	}
+	return "", fmt.Errorf("no namespace given and %s is empty", serviceAccountNamespaceFile)
+}
+
+// envOrDefault returns the value of env, or def if it is not set.
+func envOrDefault(env, def string) string {
+	if value, ok := os.LookupEnv(env); ok {
+		return value
+	}
+	return def
+}
+
 func MatchStateMap(current map[string]ResourceState, required []ResourceState) bool {
 	// do not match if no resources are available
 	if len(current) == 0 {
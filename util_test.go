@@ -1,10 +1,15 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
 )
 
 func TestGetStateDescriptionsFromEnv(t *testing.T) {
@@ -24,3 +29,75 @@ func TestGetStateDescriptionsFromEnv(t *testing.T) {
 	}
 	log.Debug(descriptions)
 }
+
+func TestSetDefaultNamespace(t *testing.T) {
+	file, err := ioutil.TempFile("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("sa-ns\n")
+	file.Close()
+	defer func(path string) { serviceAccountNamespaceFile = path }(serviceAccountNamespaceFile)
+	serviceAccountNamespaceFile = file.Name()
+	os.Unsetenv(PodNamespaceEnv)
+
+	descriptions := []StateDescription{
+		{Type: PodResource},
+		{Type: PodResource, Namespace: "explicit"},
+		{Type: PodResource, NamespaceSelector: "tier=shared"},
+	}
+	if err := SetDefaultNamespace(descriptions); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"sa-ns", "explicit", ""} {
+		if descriptions[i].Namespace != expected {
+			t.Errorf("description %d: expected namespace %q, got %q", i, expected, descriptions[i].Namespace)
+		}
+	}
+
+	os.Setenv(PodNamespaceEnv, "pod-ns")
+	defer os.Unsetenv(PodNamespaceEnv)
+	descriptions = []StateDescription{{Type: PodResource}}
+	if err := SetDefaultNamespace(descriptions); err != nil || descriptions[0].Namespace != "pod-ns" {
+		t.Fatalf("expected the namespace from %s, got %q (%v)", PodNamespaceEnv, descriptions[0].Namespace, err)
+	}
+}
+
+func TestResolveCreatedAfter(t *testing.T) {
+	started := metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	fake := fakeclientset.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubewait", Namespace: "test-ns"},
+		Status:     v1.PodStatus{StartTime: &started},
+	})
+	os.Setenv(PodNameEnv, "kubewait")
+	os.Setenv(PodNamespaceEnv, "test-ns")
+	defer os.Unsetenv(PodNameEnv)
+	defer os.Unsetenv(PodNamespaceEnv)
+
+	now := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+	descriptions := []StateDescription{
+		{CreatedAfter: ""},
+		{CreatedAfter: "2024-04-30T00:00:00Z"},
+		{CreatedAfter: "2h"},
+		{CreatedAfter: CreatedAfterPodStart},
+	}
+	if err := ResolveCreatedAfter(fake, descriptions, now); err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Time{
+		time.Time{},
+		time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+		started.Time,
+	}
+	for i := range expected {
+		if !descriptions[i].createdAfter.Equal(expected[i]) {
+			t.Errorf("%q: expected %v, got %v", descriptions[i].CreatedAfter, expected[i], descriptions[i].createdAfter)
+		}
+	}
+
+	if err := ResolveCreatedAfter(fake, []StateDescription{{CreatedAfter: "last week"}}, now); err == nil {
+		t.Fatal("expected an error for an invalid createdAfter")
+	}
+}
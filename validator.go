@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"path"
+	"time"
 
-	log "github.com/sirupsen/logrus"
 	funk "github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
 )
 
 type Validator interface {
@@ -13,14 +17,116 @@ type Validator interface {
 
 type BaseValidator struct{}
 
+// typeFields lists the fields that only apply to some resource types, by the types they apply to.
+// Every other type rejects them.
+var typeFields = map[ResourceType][]string{
+	PodResource:       {"container", "maxRestarts", "image"},
+	JobResource:       {"minSucceeded"},
+	CronJobResource:   {"minSucceeded", "run"},
+	ConfigMapResource: {"keys", "nonEmptyKeys"},
+	SecretResource:    {"keys", "nonEmptyKeys"},
+}
+
 func (BaseValidator) Validate(ctx context.Context, description StateDescription) error {
-	log.Debugf("validating: %v", description)
+	logger := description.Logger()
+	logger.Debug("validating description")
 	if len(description.RequiredStates) == 0 {
 		return ErrNoRequiredStates(description)
 	}
 	if funk.Contains(description.RequiredStates, resourceWaiting) {
-		log.Debug("description contains waiting as required state...failing")
+		logger.Debug("description contains waiting as required state...failing")
+		return ErrWaitingStateReserved(description)
+	}
+	if funk.Contains(description.FailOn, resourceWaiting) {
 		return ErrWaitingStateReserved(description)
 	}
+	for _, state := range description.FailOn {
+		if funk.Contains(description.RequiredStates, state) {
+			return ErrStateRequiredAndFailed(description, state)
+		}
+	}
+	if _, err := labels.Parse(description.LabelSelector); err != nil {
+		return ErrInvalidSelector(description, err)
+	}
+	if _, err := labels.Parse(description.NamespaceSelector); err != nil {
+		return ErrInvalidSelector(description, err)
+	}
+	// cluster-scoped resources have no namespace to look up or select by
+	if !description.Type.Namespaced() && description.Namespace != "" {
+		return ErrFieldNotValidForResourceType(description, "namespace")
+	}
+	if !description.Type.Namespaced() && description.NamespaceSelector != "" {
+		return ErrFieldNotValidForResourceType(description, "namespaceSelector")
+	}
+	if description.NamespaceSelector != "" && description.Namespace != "" && description.Namespace != AllNamespaces {
+		return ErrNamespaceSelectorWithNamespace(description)
+	}
+	if _, err := fields.ParseSelector(description.FieldSelector); err != nil {
+		return ErrInvalidSelector(description, err)
+	}
+	if _, err := path.Match(description.NameGlob, ""); err != nil {
+		return ErrInvalidNameGlob(description, err)
+	}
+	if _, err := path.Match(description.Image, ""); err != nil {
+		return ErrInvalidImage(description, err)
+	}
+	if description.MinVersion != "" {
+		if _, err := version.ParseGeneric(description.MinVersion); err != nil {
+			return ErrInvalidMinVersion(description, err)
+		}
+	}
+	if description.MinCount != nil && *description.MinCount < 1 {
+		return ErrInvalidMinCount(description)
+	}
+	for _, field := range description.typeSpecificFields() {
+		if !funk.Contains(typeFields[description.Type], field) {
+			return ErrFieldNotValidForResourceType(description, field)
+		}
+	}
+	if description.CreatedAfter != CreatedAfterPodStart {
+		if _, err := parseCreatedAfter(description.CreatedAfter, time.Now()); err != nil {
+			return ErrInvalidCreatedAfter(description, err)
+		}
+	}
+	return nil
+}
+
+// typeSpecificFields returns the JSON names of the fields in typeFields that are set.
+func (d StateDescription) typeSpecificFields() []string {
+	var fields []string
+	if d.Container != "" {
+		fields = append(fields, "container")
+	}
+	if d.MaxRestarts != nil {
+		fields = append(fields, "maxRestarts")
+	}
+	if d.Image != "" {
+		fields = append(fields, "image")
+	}
+	if d.MinSucceeded != nil {
+		fields = append(fields, "minSucceeded")
+	}
+	if d.Run != "" {
+		fields = append(fields, "run")
+	}
+	if len(d.Keys) > 0 {
+		fields = append(fields, "keys")
+	}
+	if d.NonEmptyKeys {
+		fields = append(fields, "nonEmptyKeys")
+	}
+	return fields
+}
+
+// validateStates returns an error for the first state in RequiredStates or FailOn that is not
+// one of permitted.
+func validateStates(description StateDescription, permitted []ResourceState) error {
+	for _, states := range [][]ResourceState{description.RequiredStates, description.FailOn} {
+		for _, state := range states {
+			if !funk.Contains(permitted, state) {
+				return ErrStateNotValidForResourceType(description, state)
+			}
+		}
+	}
 	return nil
 }
@@ -2,38 +2,116 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-func wait(ctx context.Context, clientset kubernetes.Interface, descriptions []StateDescription) {
+func wait(ctx context.Context, clientset kubernetes.Interface, reporter *EventReporter, progressInterval time.Duration, descriptions []StateDescription) ([]DescriptionResult, error) {
 	for _, description := range descriptions {
 		validator, ok := getValidator(clientset, description)
 		if !ok {
-			panic("could not find validator for resource type " + description.Type)
+			return nil, fmt.Errorf("could not find validator for resource type %s", description.Type)
 		}
 		if err := validator.Validate(ctx, description); err != nil {
-			panic("description not valid: " + err.Error())
+			return nil, fmt.Errorf("description not valid: %v", err)
 		}
 	}
 
-	var wg sync.WaitGroup
-	for _, description := range descriptions {
-		matcher, ok := getMatcher(clientset, description)
+	informers := NewInformerCache(clientset)
+	defer informers.Stop()
+	matchers := make([]Matcher, len(descriptions))
+	for i, description := range descriptions {
+		matcher, ok := getMatcher(informers, description)
 		if !ok {
-			panic("could not find matcher for resource type " + description.Type)
+			return nil, fmt.Errorf("could not find matcher for resource type %s", description.Type)
 		}
+		matchers[i] = matcher
+	}
+
+	// a description that cannot match any more fails the wait, so the others are stopped, with
+	// the failed description as the cause
+	waitCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var wg sync.WaitGroup
+	errs := make([]error, len(matchers))
+	for i, matcher := range matchers {
 		wg.Add(1)
-		go func() {
+		go func(i int, description StateDescription, matcher Matcher) {
 			defer wg.Done()
-			err := matcher.Start(ctx)
-			if err != nil {
-				panic(err)
+			reporter.Eventf(v1.EventTypeNormal, ReasonWaitingForDependency, "waiting for %v", description)
+			errs[i] = matcher.Start(waitCtx)
+			if errs[i] != nil && !isContextErr(errs[i]) {
+				cancel(fmt.Errorf("stopped after %v failed", description))
 			}
-		}()
+
+			switch {
+			case errs[i] == nil && description.MatchStates(matcher.States()):
+				reporter.Eventf(v1.EventTypeNormal, ReasonDependencyMatched, "matched %v", description)
+			case ctx.Err() == context.DeadlineExceeded:
+				reporter.Eventf(v1.EventTypeWarning, ReasonWaitTimedOut, "timed out waiting for %v", description)
+			case errs[i] != nil && !isContextErr(errs[i]):
+				reporter.Eventf(v1.EventTypeWarning, ReasonDependencyFailed, "%v: %v", description, errs[i])
+			}
+		}(i, descriptions[i], matcher)
 	}
+	done := make(chan struct{})
+	go reportProgress(ctx, progressInterval, descriptions, matchers, done)
+	go stopOnCancel(waitCtx, matchers, done)
 	wg.Wait()
+	close(done)
+
+	results := make([]DescriptionResult, len(descriptions))
+	unmatched := 0
+	for i, description := range descriptions {
+		states := matchers[i].States()
+		results[i] = DescriptionResult{
+			StateDescription: description,
+			Matched:          errs[i] == nil && description.MatchStates(states),
+			States:           states,
+			Reasons:          matchers[i].Reasons(),
+		}
+		// a timeout or interruption is reported through the unmatched resources rather than as an error
+		if isContextErr(errs[i]) {
+			results[i].Stopped = context.Cause(waitCtx)
+		} else {
+			results[i].Err = errs[i]
+		}
+		if !results[i].Matched {
+			unmatched++
+		}
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return results, fmt.Errorf("timed out with %d of %d descriptions not matched", unmatched, len(descriptions))
+	case ctx.Err() == context.Canceled:
+		return results, fmt.Errorf("interrupted with %d of %d descriptions not matched", unmatched, len(descriptions))
+	case unmatched > 0:
+		return results, fmt.Errorf("%d of %d descriptions not matched", unmatched, len(descriptions))
+	}
+	return results, nil
+}
+
+// stopOnCancel stops every matcher once ctx is cancelled, unless done is closed first.
+func stopOnCancel(ctx context.Context, matchers []Matcher, done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	for _, matcher := range matchers {
+		if err := matcher.Stop(context.Background()); err != nil {
+			log.Warnf("could not stop matcher: %v", err)
+		}
+	}
+}
+
+func isContextErr(err error) bool {
+	return err == context.DeadlineExceeded || err == context.Canceled
 }
 
 func getValidator(clientset kubernetes.Interface, description StateDescription) (Validator, bool) {
@@ -42,16 +120,34 @@ func getValidator(clientset kubernetes.Interface, description StateDescription)
 		return NewPodValidator(), true
 	case JobResource:
 		return NewJobValidator(), true
+	case CronJobResource:
+		return NewCronJobValidator(), true
+	case NodeResource:
+		return NewNodeValidator(), true
+	case NamespaceResource:
+		return NewNamespaceValidator(), true
+	case ConfigMapResource, SecretResource:
+		return NewKeysValidator(), true
 	}
 	return nil, false
 }
 
-func getMatcher(clientset kubernetes.Interface, description StateDescription) (Matcher, bool) {
+func getMatcher(informers *InformerCache, description StateDescription) (Matcher, bool) {
 	switch description.Type {
 	case PodResource:
-		return NewPodMatcher(clientset, description), true
+		return NewPodMatcher(informers, description), true
 	case JobResource:
-		return NewJobMatcher(clientset, description), true
+		return NewJobMatcher(informers, description), true
+	case CronJobResource:
+		return NewCronJobMatcher(informers, description), true
+	case NodeResource:
+		return NewNodeMatcher(informers, description), true
+	case NamespaceResource:
+		return NewNamespaceMatcher(informers, description), true
+	case ConfigMapResource:
+		return NewConfigMapMatcher(informers, description), true
+	case SecretResource:
+		return NewSecretMatcher(informers, description), true
 	}
 	return nil, false
 }
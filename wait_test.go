@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	testcore "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func newFakeEventReporter() (*EventReporter, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	return &EventReporter{
+		recorder: recorder,
+		pod:      &v1.ObjectReference{Kind: "Pod", Name: "kubewait", Namespace: "test-ns"},
+	}, recorder
+}
+
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, reason string) {
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, reason) {
+			t.Fatalf("expected %s event, got %q", reason, event)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("no %s event recorded", reason)
+	}
+}
+
+func TestWaitEventsMatched(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	podlist := &v1.PodList{
+		Items: []v1.Pod{
+			v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "test-ns",
+				},
+				Status: v1.PodStatus{
+					Phase: v1.PodRunning,
+					Conditions: []v1.PodCondition{
+						v1.PodCondition{
+							Type:   v1.PodReady,
+							Status: v1.ConditionTrue,
+						},
+					},
+				},
+			},
+		},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependReactor("list", "pods", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, podlist, nil
+	})
+	reporter, recorder := newFakeEventReporter()
+
+	results, err := wait(context.Background(), fake, reporter, 0, []StateDescription{description})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Matched {
+		t.Fatalf("description should have matched: %v", results[0])
+	}
+	expectEvent(t, recorder, ReasonWaitingForDependency)
+	expectEvent(t, recorder, ReasonDependencyMatched)
+}
+
+func TestWaitEventsTimedOut(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watch.NewFake(), nil))
+	reporter, recorder := newFakeEventReporter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results, err := wait(ctx, fake, reporter, 0, []StateDescription{description})
+	if err == nil {
+		t.Fatal("wait should fail after the timeout")
+	}
+	if results[0].Matched || results[0].Err != nil {
+		t.Fatalf("description should be unmatched without an error: %v", results[0])
+	}
+	expectEvent(t, recorder, ReasonWaitingForDependency)
+	expectEvent(t, recorder, ReasonWaitTimedOut)
+}
+
+func TestWaitInterrupted(t *testing.T) {
+	description := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset()
+	fake.PrependWatchReactor("pods", testcore.DefaultWatchReactor(watch.NewFake(), nil))
+	reporter, recorder := newFakeEventReporter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	results, err := wait(ctx, fake, reporter, 0, []StateDescription{description})
+	if err == nil || !strings.HasPrefix(err.Error(), "interrupted") {
+		t.Fatalf("wait should report the interruption, got %v", err)
+	}
+	if results[0].Matched || results[0].Err != nil {
+		t.Fatalf("description should be unmatched without an error: %v", results[0])
+	}
+	expectEvent(t, recorder, ReasonWaitingForDependency)
+	select {
+	case event := <-recorder.Events:
+		t.Fatalf("unexpected event after interruption: %q", event)
+	default:
+	}
+}
+
+func TestWaitFailOnStopsOtherDescriptions(t *testing.T) {
+	failed := StateDescription{
+		Type:           PodResource,
+		Namespace:      "test-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+		FailOn:         []ResourceState{ResourceRunning},
+	}
+	pending := StateDescription{
+		Type:           PodResource,
+		Namespace:      "other-ns",
+		RequiredStates: []ResourceState{ResourceReady},
+	}
+	fake := fakeclientset.NewSimpleClientset(newTestPod("pod-1", "1", false))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := wait(ctx, fake, nil, 0, []StateDescription{failed, pending})
+	if err == nil || ctx.Err() != nil {
+		t.Fatalf("wait should fail before the timeout, got %v", err)
+	}
+	if _, ok := results[0].Err.(*FailedStateError); !ok {
+		t.Fatalf("expected a failed state error, got %v", results[0].Err)
+	}
+	if results[1].Matched || results[1].Err != nil {
+		t.Fatalf("other description should be stopped without an error: %v", results[1])
+	}
+	if results[1].Stopped == nil || isContextErr(results[1].Stopped) {
+		t.Fatalf("other description should be stopped by the failure, got %v", results[1].Stopped)
+	}
+}